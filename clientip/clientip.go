@@ -0,0 +1,147 @@
+// Package clientip resolves the address of the client behind reverse proxies.
+//
+// Forwarding headers are only believed when they arrive from a trusted proxy:
+// the resolver walks X-Forwarded-For (or the RFC 7239 Forwarded header) from
+// the nearest hop outwards and stops at the first address it does not trust.
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Resolver finds the client address of a request.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// NewResolver returns a Resolver that trusts forwarding headers sent by
+// proxies in cidrs. Plain addresses are accepted as single-host ranges. With
+// no cidrs the socket address is always used.
+func NewResolver(cidrs ...string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				return nil, fmt.Errorf("clientip: invalid trusted proxy %q: %w", cidr, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+	return r, nil
+}
+
+// Trusted reports whether addr belongs to a trusted proxy.
+func (r *Resolver) Trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client address of req. Forwarding headers are only
+// used when the peer is a trusted proxy, and only as far back as the chain of
+// trusted proxies goes.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	peer, ok := parseAddr(req.RemoteAddr)
+	if !ok {
+		return req.RemoteAddr
+	}
+	if !r.Trusted(peer) {
+		return peer.String()
+	}
+
+	hops, ok := forwardedFor(req.Header)
+	if !ok {
+		if realIP, ok := parseAddr(strings.TrimSpace(req.Header.Get("X-Real-IP"))); ok {
+			return realIP.String()
+		}
+		return peer.String()
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseAddr(hops[i])
+		if !ok {
+			break
+		}
+		client = hop
+		if !r.Trusted(hop) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// forwardedFor returns the client chain from the Forwarded header, or from
+// X-Forwarded-For when there is no Forwarded header. The nearest hop is last.
+func forwardedFor(h http.Header) ([]string, bool) {
+	if values := h.Values("Forwarded"); len(values) > 0 {
+		var hops []string
+		for _, value := range values {
+			for _, element := range strings.Split(value, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					key, val, _ := strings.Cut(strings.TrimSpace(pair), "=")
+					if strings.EqualFold(key, "for") {
+						hops = append(hops, strings.Trim(val, `"`))
+					}
+				}
+			}
+		}
+		return hops, len(hops) > 0
+	}
+
+	var hops []string
+	for _, value := range h.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops, len(hops) > 0
+}
+
+// parseAddr accepts an address with or without a port, and IPv6 addresses in
+// brackets.
+func parseAddr(s string) (netip.Addr, bool) {
+	if addr, err := netip.ParseAddr(strings.Trim(s, "[]")); err == nil {
+		return addr.Unmap(), true
+	}
+	host, _, err := net.SplitHostPort(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+type resolverKey struct{}
+
+var defaultResolver = &Resolver{}
+
+// ContextWithResolver returns a copy of ctx carrying r.
+func ContextWithResolver(ctx context.Context, r *Resolver) context.Context {
+	return context.WithValue(ctx, resolverKey{}, r)
+}
+
+// FromRequest returns the client address of req using the Resolver stored in
+// its context. Without one, no proxy is trusted and the socket address is used.
+func FromRequest(req *http.Request) string {
+	r, ok := req.Context().Value(resolverKey{}).(*Resolver)
+	if !ok {
+		r = defaultResolver
+	}
+	return r.ClientIP(req)
+}
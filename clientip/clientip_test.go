@@ -0,0 +1,68 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	resolver, err := NewResolver("10.0.0.0/8", "192.168.1.1")
+	if err != nil {
+		t.Fatalf("NewResolver returned error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{"untrusted peer ignores headers", "203.0.113.9:1234", map[string]string{"X-Forwarded-For": "1.1.1.1"}, "203.0.113.9"},
+		{"no headers", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"single proxy", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+		{"spoofed left entries are skipped", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.7, 10.0.0.2"}, "198.51.100.7"},
+		{"all trusted returns leftmost", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "10.0.0.3, 192.168.1.1"}, "10.0.0.3"},
+		{"invalid hop stops the walk", "10.0.0.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.7, garbage"}, "10.0.0.1"},
+		{"x-real-ip", "192.168.1.1:1234", map[string]string{"X-Real-IP": "198.51.100.8"}, "198.51.100.8"},
+		{"forwarded header", "10.0.0.1:1234", map[string]string{
+			"Forwarded":       `for=6.6.6.6, for="[2001:db8:cafe::17]:4711";proto=https`,
+			"X-Forwarded-For": "1.1.1.1",
+		}, "2001:db8:cafe::17"},
+		{"ipv4-mapped peer", "[::ffff:10.0.0.1]:1234", map[string]string{"X-Forwarded-For": "198.51.100.7"}, "198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			if got := resolver.ClientIP(r); got != tt.want {
+				t.Fatalf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFromRequestWithoutResolverTrustsNoOne(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.7")
+
+	if got := FromRequest(r); got != "10.0.0.1" {
+		t.Fatalf("Expected socket address, got %s", got)
+	}
+
+	resolver, _ := NewResolver("10.0.0.0/8")
+	r = r.WithContext(ContextWithResolver(r.Context(), resolver))
+	if got := FromRequest(r); got != "198.51.100.7" {
+		t.Fatalf("Expected forwarded address, got %s", got)
+	}
+}
+
+func TestInvalidTrustedProxy(t *testing.T) {
+	if _, err := NewResolver("not-a-cidr"); err == nil {
+		t.Fatal("Expected an error for an invalid range")
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -42,13 +41,12 @@ var (
 //
 // Returns:
 //
-// Sends a JSON response with the created user data or an error if the input is invalid.
+// Sends a JSON response with the created user data. Invalid input is answered
+// with a 400 listing each failing field.
 func CreateUser(req *req.Request, res *req.Response) {
 	var user User
-	err := json.Unmarshal([]byte(req.BodyAsString()), &user)
-	if err != nil {
-		res.Status(http.StatusBadRequest).JSONError("Invalid input")
-		log.Println("Error decoding JSON:", err)
+	if err := req.Bind(&user); err != nil {
+		log.Println("Error binding user:", err)
 		return
 	}
 	user.ID = nextID
@@ -95,10 +93,8 @@ func UpdateUser(req *req.Request, res *req.Response) {
 	}
 
 	var updatedUser User
-	err = json.Unmarshal([]byte(req.BodyAsString()), &updatedUser)
-	if err != nil {
-		res.Status(http.StatusBadRequest).JSONError("Invalid input")
-		log.Println("Error decoding JSON:", err)
+	if err := req.Bind(&updatedUser); err != nil {
+		log.Println("Error binding user:", err)
 		return
 	}
 
@@ -0,0 +1,204 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BrunoCiccarino/GopherLight/req"
+)
+
+// DefaultTimeout is the time a single check may take before it is reported as failed.
+const DefaultTimeout = 5 * time.Second
+
+// DefaultDrainDelay is how long shutdown waits after readiness starts failing.
+// It covers a few probes of a typical load balancer health check.
+const DefaultDrainDelay = 5 * time.Second
+
+// ErrShuttingDown is reported by the readiness endpoint once draining has started.
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// Check reports whether a dependency is healthy. It should honor ctx cancellation.
+type Check func(ctx context.Context) error
+
+// Checker holds the named liveness and readiness checks of an application.
+type Checker struct {
+	// Timeout bounds how long each check may run.
+	Timeout time.Duration
+
+	// DrainDelay is how long shutdown waits after readiness starts failing,
+	// giving load balancers time to stop routing traffic before connections close.
+	// Set it to 0 to shut down at once.
+	DrainDelay time.Duration
+
+	mu        sync.RWMutex
+	liveness  []namedCheck
+	readiness []namedCheck
+	draining  atomic.Bool
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Report is the JSON document returned by the health endpoints.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of a single named check.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+// New creates a Checker with the default timeout and drain delay and no checks.
+func New() *Checker {
+	return &Checker{Timeout: DefaultTimeout, DrainDelay: DefaultDrainDelay}
+}
+
+// AddLivenessCheck registers a check reported by the liveness endpoint.
+func (c *Checker) AddLivenessCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness = append(c.liveness, namedCheck{name: name, check: check})
+}
+
+// AddReadinessCheck registers a check reported by the readiness endpoint.
+func (c *Checker) AddReadinessCheck(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readiness = append(c.readiness, namedCheck{name: name, check: check})
+}
+
+// Drain marks the application as shutting down. From then on the readiness
+// endpoint reports unhealthy regardless of its checks.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Draining reports whether Drain has been called.
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Liveness runs the liveness checks and returns their report.
+func (c *Checker) Liveness(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]namedCheck(nil), c.liveness...)
+	c.mu.RUnlock()
+	return c.run(ctx, checks)
+}
+
+// Readiness runs the readiness checks and returns their report. Once draining,
+// it fails immediately without running the checks.
+func (c *Checker) Readiness(ctx context.Context) Report {
+	if c.Draining() {
+		return Report{
+			Status: statusFail,
+			Checks: map[string]CheckResult{
+				"shutdown": {Status: statusFail, Error: ErrShuttingDown.Error(), Duration: "0s"},
+			},
+		}
+	}
+	c.mu.RLock()
+	checks := append([]namedCheck(nil), c.readiness...)
+	c.mu.RUnlock()
+	return c.run(ctx, checks)
+}
+
+// LivenessHandler serves the liveness report, with status 503 when a check fails.
+func (c *Checker) LivenessHandler(r *req.Request, w *req.Response) {
+	writeReport(w, c.Liveness(r.Req.Context()))
+}
+
+// ReadinessHandler serves the readiness report, with status 503 when a check fails
+// or the application is draining.
+func (c *Checker) ReadinessHandler(r *req.Request, w *req.Response) {
+	writeReport(w, c.Readiness(r.Req.Context()))
+}
+
+// run executes checks concurrently, each bounded by the checker timeout.
+func (c *Checker) run(ctx context.Context, checks []namedCheck) Report {
+	report := Report{Status: statusOK, Checks: make(map[string]CheckResult, len(checks))}
+	if len(checks) == 0 {
+		return report
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, nc := range checks {
+		wg.Add(1)
+		go func(nc namedCheck) {
+			defer wg.Done()
+			result := c.runOne(ctx, nc.check)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[nc.name] = result
+			if result.Status != statusOK {
+				report.Status = statusFail
+			}
+		}(nc)
+	}
+	wg.Wait()
+	return report
+}
+
+// runOne executes a single check, giving up once the timeout expires even if
+// the check ignores its context. A check that panics is reported as failed.
+func (c *Checker) runOne(ctx context.Context, check Check) CheckResult {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{Status: statusOK, Duration: time.Since(start).String()}
+	if err != nil {
+		result.Status = statusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func writeReport(w *req.Response, report Report) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != statusOK {
+		w.Status(http.StatusServiceUnavailable)
+	}
+	w.JSON(report)
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/BrunoCiccarino/GopherLight/req"
+)
+
+func TestReadinessReportsFailingCheck(t *testing.T) {
+	checker := New()
+	checker.AddReadinessCheck("db", func(ctx context.Context) error { return nil })
+	checker.AddReadinessCheck("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	r, w, rec := req.NewTestRequest("GET", "/readyz", nil)
+	checker.ReadinessHandler(r, w)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if ct := rec.Result().Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Expected application/json, got %s", ct)
+	}
+
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Error unmarshalling report: %v", err)
+	}
+	if report.Checks["db"].Status != "ok" {
+		t.Fatalf("Expected db check to pass, got %+v", report.Checks["db"])
+	}
+	if report.Checks["cache"].Error != "connection refused" {
+		t.Fatalf("Expected cache check error, got %+v", report.Checks["cache"])
+	}
+}
+
+func TestChecksRunConcurrentlyWithTimeout(t *testing.T) {
+	checker := New()
+	checker.Timeout = 50 * time.Millisecond
+	for _, name := range []string{"a", "b", "c"} {
+		checker.AddLivenessCheck(name, func(ctx context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		})
+	}
+
+	start := time.Now()
+	report := checker.Liveness(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("Expected checks to time out concurrently, took %v", elapsed)
+	}
+	if report.Status != "fail" {
+		t.Fatalf("Expected timed out checks to fail, got %s", report.Status)
+	}
+}
+
+func TestDrainFailsReadinessOnly(t *testing.T) {
+	checker := New()
+	checker.Drain()
+
+	r, w, rec := req.NewTestRequest("GET", "/readyz", nil)
+	checker.ReadinessHandler(r, w)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected readiness status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	r, w, rec = req.NewTestRequest("GET", "/livez", nil)
+	checker.LivenessHandler(r, w)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected liveness status %d, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestPanickingCheckFails(t *testing.T) {
+	checker := New()
+	checker.AddLivenessCheck("broken", func(ctx context.Context) error { panic("nil map") })
+
+	report := checker.Liveness(context.Background())
+	if report.Status != "fail" || report.Checks["broken"].Error != "check panicked: nil map" {
+		t.Fatalf("Expected the panicking check to fail, got %+v", report)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/BrunoCiccarino/GopherLight/clientip"
 )
 
 func LogInfo(message string) {
@@ -31,8 +33,8 @@ func LogDebug(message string) {
 }
 
 func LogRequest(r *http.Request, status int, duration time.Duration) {
-	log.Printf("[REQUEST] Method: %s | Path: %s | Status: %d | Duration: %v | User-Agent: %s",
-		r.Method, r.URL.Path, status, duration, r.UserAgent())
+	log.Printf("[REQUEST] Method: %s | Path: %s | Status: %d | Duration: %v | IP: %s | User-Agent: %s",
+		r.Method, r.URL.Path, status, duration, clientip.FromRequest(r), r.UserAgent())
 }
 
 func CheckCriticalError(err error, context string) {
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/BrunoCiccarino/GopherLight/req"
 	"github.com/golang-jwt/jwt/v4"
 )
 
@@ -15,8 +16,9 @@ type JWTConfig struct {
 	TokenExtractor func(r *http.Request) (string, error)
 }
 
+// DefaultErrorHandler answers a rejected request with RFC 7807 problem details.
 func DefaultErrorHandler(w http.ResponseWriter, message string, code int) {
-	http.Error(w, message, code)
+	req.NewResponse(w).Problem(req.NewProblem(code, message))
 }
 
 func DefaultTokenExtractor(r *http.Request) (string, error) {
@@ -31,6 +33,10 @@ func DefaultTokenExtractor(r *http.Request) (string, error) {
 	return tokenString, nil
 }
 
+// JWTClaimsKey is the key under which NewAuthMiddleware stores the claims of a
+// valid token. Read them in a handler with req.Get[jwt.MapClaims](r, JWTClaimsKey).
+const JWTClaimsKey = "jwt.claims"
+
 func NewAuthMiddleware(config JWTConfig) func(http.HandlerFunc) http.HandlerFunc {
 	if config.ErrorHandler == nil {
 		config.ErrorHandler = DefaultErrorHandler
@@ -52,6 +58,8 @@ func NewAuthMiddleware(config JWTConfig) func(http.HandlerFunc) http.HandlerFunc
 				return
 			}
 
+			r = req.WithLocals(r)
+			req.Set(r, JWTClaimsKey, token.Claims)
 			next(w, r)
 		}
 	}
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/BrunoCiccarino/GopherLight/req"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 )
@@ -32,11 +33,14 @@ func TestAuthMiddleware(t *testing.T) {
 	reqValid.Header.Set("Authorization", "Bearer "+token)
 	recorderValid := httptest.NewRecorder()
 
+	var claims jwt.MapClaims
 	handler := NewAuthMiddleware(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ = req.Get[jwt.MapClaims](r, JWTClaimsKey)
 		w.WriteHeader(http.StatusOK)
 	}))
 	handler.ServeHTTP(recorderValid, reqValid)
 	assert.Equal(t, http.StatusOK, recorderValid.Code)
+	assert.NotNil(t, claims["exp"])
 
 	reqInvalid := httptest.NewRequest("GET", "/", nil)
 	reqInvalid.Header.Set("Authorization", "Bearer invalid-token")
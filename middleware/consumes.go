@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/BrunoCiccarino/GopherLight/req"
+	"github.com/BrunoCiccarino/GopherLight/router"
+)
+
+// ConsumesMiddleware rejects request bodies whose Content-Type is not one of
+// types with a 415 Unsupported Media Type problem. Types may use a wildcard
+// subtype, such as "image/*". Requests without a body are let through.
+func ConsumesMiddleware(types ...string) router.Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+				next(w, r)
+				return
+			}
+
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || !consumes(types, mediaType) {
+				_, res := req.New(w, r)
+				res.Problem(req.NewProblem(http.StatusUnsupportedMediaType, "Content-Type must be one of "+strings.Join(types, ", ")))
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+func consumes(types []string, mediaType string) bool {
+	for _, t := range types {
+		if req.MatchMediaType(t, mediaType) {
+			return true
+		}
+	}
+	return false
+}
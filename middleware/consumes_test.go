@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BrunoCiccarino/GopherLight/req"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumesMiddleware(t *testing.T) {
+	handler := ConsumesMiddleware("application/json", "image/*")(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		status      int
+	}{
+		{"matching type", "{}", "application/json; charset=utf-8", http.StatusOK},
+		{"wildcard subtype", "png", "image/png", http.StatusOK},
+		{"unsupported type", "a=b", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{"missing type", "{}", "", http.StatusUnsupportedMediaType},
+		{"no body", "", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			recorder := httptest.NewRecorder()
+
+			handler(recorder, request)
+
+			assert.Equal(t, tt.status, recorder.Code)
+			if tt.status == http.StatusUnsupportedMediaType {
+				assert.Equal(t, req.ProblemContentType, recorder.Result().Header.Get("Content-Type"))
+				assert.Contains(t, recorder.Body.String(), "application/json, image/*")
+			}
+		})
+	}
+}
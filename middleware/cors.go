@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/BrunoCiccarino/GopherLight/req"
 )
 
 // CORSOptions holds the configuration settings for Cross-Origin Resource Sharing (CORS).
@@ -45,19 +47,29 @@ var DefaultCORSOptions = CORSOptions{
 	MaxAge:           600,
 }
 
+// AllowsOrigin reports whether origin is the configured AllowOrigin, so
+// WebSocket upgrades can apply the same policy through ws.WithCORS. A "*"
+// AllowOrigin matches no origin here: browsers send cookies with WebSocket
+// handshakes, so allowing every site would let any page act as the user.
+func (opts CORSOptions) AllowsOrigin(origin string) bool {
+	return opts.AllowOrigin != "*" && origin == opts.AllowOrigin
+}
+
 // CORSMiddleware creates a middleware function that applies the given CORS options.
 func CORSMiddleware(opts CORSOptions) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			originHeader := r.Header.Get("Origin")
 
-			if (originHeader != opts.AllowOrigin) && opts.AllowOrigin != "*" {
-				http.Error(w, "CORS Origin not allowed", http.StatusForbidden)
+			if opts.AllowOrigin != "*" && !opts.AllowsOrigin(originHeader) {
+				_, res := req.New(w, r)
+				res.Problem(req.NewProblem(http.StatusForbidden, "CORS Origin not allowed"))
 				return
 			}
 
 			if opts.AllowOrigin == "*" && opts.AllowCredentials {
-				http.Error(w, "Could not set Access-Control-Allow-Credentials to true when Access-Control-Allow-Origin is *", http.StatusForbidden)
+				_, res := req.New(w, r)
+				res.Problem(req.NewProblem(http.StatusForbidden, "Could not set Access-Control-Allow-Credentials to true when Access-Control-Allow-Origin is *"))
 				return
 			}
 
@@ -5,6 +5,8 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/BrunoCiccarino/GopherLight/req"
+	"github.com/BrunoCiccarino/GopherLight/ws"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,4 +40,61 @@ func TestCORSMiddlewareOriginNotAllowed(t *testing.T) {
 
 	handler.ServeHTTP(recorder, request)
 	assert.Equal(t, http.StatusForbidden, recorder.Result().StatusCode)
+	assert.Equal(t, req.ProblemContentType, recorder.Result().Header.Get("Content-Type"))
+}
+
+func TestCORSMiddlewareWildcardAllowsAnyOrigin(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("Origin", "https://anywhere.example")
+
+	handler := CORSMiddleware(DefaultCORSOptions)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+}
+
+func TestCORSOptionsAllowsOrigin(t *testing.T) {
+	opts := CORSOptions{AllowOrigin: "https://app.example"}
+	assert.True(t, opts.AllowsOrigin("https://app.example"))
+	assert.False(t, opts.AllowsOrigin("https://evil.example"))
+	assert.False(t, DefaultCORSOptions.AllowsOrigin("https://anywhere.example"))
+}
+
+func TestCORSOptionsWithWebSocket(t *testing.T) {
+	handshake := func(opts CORSOptions, origin func(url string) string) int {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if conn, err := ws.Upgrade(w, r, ws.WithCORS(opts)); err == nil {
+				conn.Close(ws.CloseNormal, "")
+			}
+		}))
+		defer srv.Close()
+
+		request, _ := http.NewRequest("GET", srv.URL, nil)
+		request.Header.Set("Connection", "Upgrade")
+		request.Header.Set("Upgrade", "websocket")
+		request.Header.Set("Sec-WebSocket-Version", "13")
+		request.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		request.Header.Set("Origin", origin(srv.URL))
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("Handshake failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	self := func(url string) string { return url }
+	fixed := func(origin string) func(string) string {
+		return func(string) string { return origin }
+	}
+
+	dashboard := CORSOptions{AllowOrigin: "https://dashboard.example"}
+	assert.Equal(t, http.StatusSwitchingProtocols, handshake(dashboard, self))
+	assert.Equal(t, http.StatusSwitchingProtocols, handshake(dashboard, fixed("https://dashboard.example")))
+	assert.Equal(t, http.StatusForbidden, handshake(dashboard, fixed("https://evil.example")))
+
+	assert.Equal(t, http.StatusSwitchingProtocols, handshake(DefaultCORSOptions, self))
+	assert.Equal(t, http.StatusForbidden, handshake(DefaultCORSOptions, fixed("https://evil.example")))
 }
@@ -4,13 +4,16 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"net/http"
+
+	"github.com/BrunoCiccarino/GopherLight/req"
 )
 
 func CSRFMiddleware(next http.HandlerFunc, isValidToken func(string) bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		csrfToken := r.Header.Get("X-CSRF-Token")
 		if csrfToken == "" || !isValidToken(csrfToken) {
-			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			_, res := req.New(w, r)
+			res.Problem(req.NewProblem(http.StatusForbidden, "Invalid CSRF token"))
 			return
 		}
 		next(w, r)
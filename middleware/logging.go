@@ -1,10 +1,12 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/BrunoCiccarino/GopherLight/logger"
+	"github.com/BrunoCiccarino/GopherLight/req"
 )
 
 func LoggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
@@ -12,10 +14,16 @@ func LoggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		start := time.Now()
 		logger.LogInfo("Started " + r.Method + " " + r.URL.Path)
 
-		next(w, r)
+		rec := req.Record(w)
+		next(rec, r)
 
 		duration := time.Since(start)
-		logger.LogRequest(r, http.StatusOK, duration)
-		logger.LogInfo("Completed " + r.URL.Path + " in " + duration.String())
+		status := rec.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		logger.LogRequest(r, status, duration)
+		logger.LogInfo(fmt.Sprintf("Completed %s in %s (%d bytes, first byte after %s)",
+			r.URL.Path, duration, rec.BytesWritten(), rec.TimeToFirstByte()))
 	}
 }
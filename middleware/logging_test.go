@@ -5,8 +5,12 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
+
+	"github.com/BrunoCiccarino/GopherLight/req"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestLoggingMiddleware(t *testing.T) {
@@ -38,3 +42,21 @@ func TestLoggingMiddleware(t *testing.T) {
 		t.Errorf("Expected status OK, got %v", w.Code)
 	}
 }
+
+func TestLoggingMiddlewareLogsStatus(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	handler := LoggingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		res := req.NewResponse(w)
+		res.Status(http.StatusNotFound).Send("missing")
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, buf.String(), "Status: 404")
+	assert.Contains(t, buf.String(), "(7 bytes")
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/BrunoCiccarino/GopherLight/logger"
+	"github.com/BrunoCiccarino/GopherLight/req"
+	"github.com/BrunoCiccarino/GopherLight/router"
+)
+
+// PanicHandler writes the response for a request whose handler panicked with
+// the value recovered.
+type PanicHandler func(w http.ResponseWriter, r *http.Request, recovered interface{})
+
+// RecoveryMiddleware recovers from panics in the handlers it wraps, logs the
+// panic with its stack trace and answers with a 500 problem details response.
+func RecoveryMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return RecoveryMiddlewareWithHandler(nil)(next)
+}
+
+// RecoveryMiddlewareWithHandler is RecoveryMiddleware with a custom response.
+// The handler is only called if the response has not been started; a nil
+// handler sends the default 500.
+func RecoveryMiddlewareWithHandler(handler PanicHandler) router.Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			rec := req.Record(w)
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				stack := debug.Stack()
+				if p, ok := recovered.(*goroutinePanic); ok {
+					recovered, stack = p.value, p.stack
+				}
+				logger.LogError(fmt.Sprintf("Panic serving %s %s: %v\n%s", r.Method, r.URL.Path, recovered, stack))
+
+				if rec.Written() {
+					return
+				}
+				if handler != nil {
+					handler(rec, r, recovered)
+					return
+				}
+				_, res := req.New(rec, r)
+				res.Problem(req.NewProblem(http.StatusInternalServerError, ""))
+			}()
+
+			next(rec, r)
+		}
+	}
+}
+
+// goroutinePanic carries a panic from a handler goroutine, with the stack
+// trace of that goroutine, to the goroutine serving the request.
+type goroutinePanic struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *goroutinePanic) String() string {
+	return fmt.Sprintf("%v\n%s", p.value, p.stack)
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	handler := RecoveryMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		panic("something went wrong")
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/boom", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.Contains(t, buf.String(), "Panic serving GET /boom: something went wrong")
+	assert.Contains(t, buf.String(), "recovery_test.go")
+}
+
+func TestRecoveryMiddlewareWithHandler(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	defer log.SetOutput(os.Stderr)
+
+	var recovered interface{}
+	handler := RecoveryMiddlewareWithHandler(func(w http.ResponseWriter, r *http.Request, p interface{}) {
+		recovered = p
+		http.Error(w, "custom", http.StatusServiceUnavailable)
+	})(func(w http.ResponseWriter, r *http.Request) {
+		panic(42)
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, 42, recovered)
+}
+
+func TestRecoveryMiddlewareAfterWrite(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	defer log.SetOutput(os.Stderr)
+
+	handler := RecoveryMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late")
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestRecoveryMiddlewareTimeoutGoroutine(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	handler := RecoveryMiddleware(TimeoutMiddleware(time.Second)(func(w http.ResponseWriter, r *http.Request) {
+		panic("panic in handler goroutine")
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, buf.String(), "panic in handler goroutine")
+	assert.Contains(t, buf.String(), "recovery_test.go")
+}
@@ -1,37 +1,236 @@
 package middleware
 
 import (
+	"bufio"
 	"context"
+	"net"
 	"net/http"
+	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/BrunoCiccarino/GopherLight/logger"
+	"github.com/BrunoCiccarino/GopherLight/req"
 	"github.com/BrunoCiccarino/GopherLight/router"
 )
 
+// TimeoutMiddleware answers with a 504 Gateway Timeout problem when the
+// handler takes longer than timeout. The handler runs in its own goroutine with a context
+// that is cancelled at the deadline; anything it writes after that is
+// discarded. A panic in the handler is raised again in the goroutine serving
+// the request, where RecoveryMiddleware can handle it. A handler that
+// streams, as res.SSE does, lifts the deadline with req.ReleaseTimeout, and
+// hijacking the connection for a WebSocket lifts it too.
 func TimeoutMiddleware(timeout time.Duration) router.Middleware {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			ctx, cancel := context.WithTimeout(r.Context(), timeout)
-			defer cancel()
+			ctx := newTimeoutContext(r.Context(), timeout)
+			defer ctx.cancel(context.Canceled)
 
-			r = r.WithContext(ctx)
+			r = r.WithContext(req.ContextWithTimeoutRelease(ctx, ctx.release))
+			tw := &timeoutWriter{w: w, ctx: ctx, header: w.Header().Clone()}
 			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
 
 			go func() {
-				next(w, r)
+				defer func() {
+					if p := recover(); p != nil {
+						if p != http.ErrAbortHandler {
+							p = &goroutinePanic{value: p, stack: debug.Stack()}
+						}
+						tw.mu.Lock()
+						defer tw.mu.Unlock()
+						if tw.expired() {
+							logger.LogError("Panic after timeout serving " + r.URL.Path + ": " + panicString(p))
+							return
+						}
+						panicked <- p
+					}
+				}()
+				next(tw, r)
 				close(done)
 			}()
 
 			select {
 			case <-done:
-
-				logger.LogInfo("Request completed within timeout")
+			case p := <-panicked:
+				panic(p)
 			case <-ctx.Done():
+			}
 
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			select {
+			case p := <-panicked:
+				panic(p)
+			default:
+			}
+			if !tw.expired() {
+				// Headers set without writing the response still go out
+				// with the implicit 200.
+				if !tw.wroteHeader {
+					tw.copyHeader()
+				}
+				logger.LogInfo("Request completed within timeout")
+				return
+			}
+			if ctx.Err() == context.DeadlineExceeded {
 				logger.LogError("Request timed out for " + r.URL.Path)
-				http.Error(w, "Request Timeout", http.StatusGatewayTimeout)
+				if !tw.wroteHeader {
+					_, res := req.New(w, r)
+					res.Problem(req.NewProblem(http.StatusGatewayTimeout, "The request took too long to complete."))
+				}
 			}
 		}
 	}
 }
+
+// timeoutContext is cancelled at its deadline, like one from
+// context.WithTimeout, unless the deadline is released first. After that
+// it only ends with its parent.
+type timeoutContext struct {
+	context.Context
+	cancel   context.CancelCauseFunc
+	deadline time.Time
+	timer    *time.Timer
+
+	mu       sync.Mutex
+	released bool
+}
+
+func newTimeoutContext(parent context.Context, timeout time.Duration) *timeoutContext {
+	ctx, cancel := context.WithCancelCause(parent)
+	c := &timeoutContext{Context: ctx, cancel: cancel, deadline: time.Now().Add(timeout)}
+	c.timer = time.AfterFunc(timeout, func() { cancel(context.DeadlineExceeded) })
+	return c
+}
+
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if parent, ok := c.Context.Deadline(); ok && (c.released || parent.Before(c.deadline)) {
+		return parent, true
+	}
+	if c.released {
+		return time.Time{}, false
+	}
+	return c.deadline, true
+}
+
+func (c *timeoutContext) Err() error {
+	err := c.Context.Err()
+	if err != nil && context.Cause(c.Context) == context.DeadlineExceeded {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// release stops the deadline timer. It reports false if the deadline has
+// already passed.
+func (c *timeoutContext) release() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.released && !c.timer.Stop() {
+		return false
+	}
+	c.released = true
+	return true
+}
+
+// timeoutWriter guards the response from the handler goroutine once the
+// request has timed out or been cancelled. The handler gets its own header
+// map, copied to the response when the status is written.
+type timeoutWriter struct {
+	w           http.ResponseWriter
+	ctx         *timeoutContext
+	header      http.Header
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(statusCode)
+}
+
+// expired reports whether the handler's context is done, after which nothing
+// more is written. The caller holds tw.mu.
+func (tw *timeoutWriter) expired() bool {
+	if !tw.timedOut && tw.ctx.Err() != nil {
+		tw.timedOut = true
+	}
+	return tw.timedOut
+}
+
+func (tw *timeoutWriter) writeHeader(statusCode int) {
+	if tw.expired() || tw.wroteHeader {
+		return
+	}
+	tw.copyHeader()
+	if statusCode >= 200 || statusCode == http.StatusSwitchingProtocols {
+		tw.wroteHeader = true
+	}
+	tw.w.WriteHeader(statusCode)
+}
+
+// copyHeader replaces the response headers with the handler's.
+func (tw *timeoutWriter) copyHeader() {
+	dst := tw.w.Header()
+	for key := range dst {
+		if _, ok := tw.header[key]; !ok {
+			delete(dst, key)
+		}
+	}
+	for key, values := range tw.header {
+		dst[key] = values
+	}
+}
+
+func (tw *timeoutWriter) Write(data []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeader(http.StatusOK)
+	return tw.w.Write(data)
+}
+
+// Flush sends any buffered data to the client unless the request timed out.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() {
+		return
+	}
+	tw.writeHeader(http.StatusOK)
+	http.NewResponseController(tw.w).Flush()
+}
+
+// Hijack takes over the connection, as a WebSocket upgrade does, and lifts
+// the deadline since the handler now owns the connection.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.expired() || !tw.ctx.release() {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	conn, rw, err := http.NewResponseController(tw.w).Hijack()
+	if err == nil {
+		tw.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+func panicString(p interface{}) string {
+	if gp, ok := p.(*goroutinePanic); ok {
+		return gp.String()
+	}
+	return "http: abort handler"
+}
@@ -1,10 +1,22 @@
 package middleware
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
+
+	"github.com/BrunoCiccarino/GopherLight/req"
+	"github.com/BrunoCiccarino/GopherLight/router"
+	"github.com/BrunoCiccarino/GopherLight/ws"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestTimeoutMiddleware(t *testing.T) {
@@ -15,12 +27,142 @@ func TestTimeoutMiddleware(t *testing.T) {
 
 	timeoutMiddleware := TimeoutMiddleware(1 * time.Second)(handler)
 
-	req := httptest.NewRequest("GET", "https://httpbin.org/delay/2", nil)
+	request := httptest.NewRequest("GET", "https://httpbin.org/delay/2", nil)
 	w := httptest.NewRecorder()
 
-	timeoutMiddleware.ServeHTTP(w, req)
+	timeoutMiddleware.ServeHTTP(w, request)
 
 	if status := w.Code; status != http.StatusGatewayTimeout {
 		t.Errorf("handler returned wrong status: expected %v, got %v", http.StatusGatewayTimeout, status)
 	}
+	assert.Equal(t, req.ProblemContentType, w.Header().Get("Content-Type"))
+}
+
+func TestTimeoutMiddlewareKeepsHeadersWithoutWrite(t *testing.T) {
+	handler := TimeoutMiddleware(time.Second)(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Foo", "bar")
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "bar", w.Header().Get("X-Foo"))
+}
+
+func TestTimeoutMiddlewareDiscardsLateWrites(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	defer log.SetOutput(os.Stderr)
+
+	finished := make(chan error)
+	handler := TimeoutMiddleware(50 * time.Millisecond)(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.Header().Set("X-Late", "yes")
+		_, err := w.Write([]byte("late"))
+		finished <- err
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, http.ErrHandlerTimeout, <-finished)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Empty(t, w.Header().Get("X-Late"))
+	assert.NotContains(t, w.Body.String(), "late")
+}
+
+func TestTimeoutMiddlewareKeepsHandlerResponse(t *testing.T) {
+	handler := TimeoutMiddleware(time.Second)(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	assert.Equal(t, "created", w.Body.String())
+}
+
+func TestTimeoutMiddlewareReleasedByHandler(t *testing.T) {
+	handler := TimeoutMiddleware(10 * time.Millisecond)(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, req.ReleaseTimeout(r))
+		_, hasDeadline := r.Context().Deadline()
+		assert.False(t, hasDeadline)
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(t, r.Context().Err())
+		w.Write([]byte("data: still here\n\n"))
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "data: still here\n\n", w.Body.String())
+}
+
+func TestTimeoutMiddlewareIgnoresClientHeaders(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	defer log.SetOutput(os.Stderr)
+
+	released := make(chan bool, 1)
+	handler := TimeoutMiddleware(10 * time.Millisecond)(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		assert.Equal(t, context.DeadlineExceeded, r.Context().Err())
+		released <- req.ReleaseTimeout(r)
+	})
+
+	r := httptest.NewRequest("GET", "/slow", nil)
+	r.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.False(t, <-released)
+}
+
+func TestTimeoutMiddlewareLiftedByWebSocket(t *testing.T) {
+	app := router.NewApp()
+	app.Use(TimeoutMiddleware(20 * time.Millisecond))
+	app.WebSocket("/ws", func(conn *ws.Conn) {
+		time.Sleep(60 * time.Millisecond)
+		conn.WriteMessage(ws.TextMessage, []byte("still here"))
+	})
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: "+srv.Listener.Addr().String()+"\r\n"+
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	frame := make([]byte, 12)
+	_, err = io.ReadFull(br, frame)
+	assert.NoError(t, err)
+	assert.Equal(t, "still here", string(frame[2:]))
+}
+
+func TestTimeoutMiddlewareIgnoresUpgradeHeaders(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	defer log.SetOutput(os.Stderr)
+
+	handler := TimeoutMiddleware(10 * time.Millisecond)(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	r := httptest.NewRequest("GET", "/slow", nil)
+	r.Header.Set("Connection", "upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
 }
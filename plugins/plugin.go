@@ -1,9 +1,28 @@
 package plugins
 
 import (
+	"context"
+
 	"github.com/BrunoCiccarino/GopherLight/req"
 )
 
 type Plugin interface {
 	Register(route func(method, path string, handler req.Handler))
 }
+
+// Hook is a function run at a stage of the application lifecycle.
+type Hook func(ctx context.Context) error
+
+// Lifecycle lets plugins attach hooks to the application lifecycle.
+type Lifecycle interface {
+	OnStart(hook Hook)
+	OnReady(hook Hook)
+	OnShutdown(hook Hook)
+	OnStop(hook Hook)
+}
+
+// LifecyclePlugin is implemented by plugins that also register lifecycle hooks.
+type LifecyclePlugin interface {
+	Plugin
+	RegisterHooks(lc Lifecycle)
+}
@@ -0,0 +1,312 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHeaderTimeout is how long a trusted peer may take to send its PROXY header.
+const DefaultHeaderTimeout = 10 * time.Second
+
+var (
+	// ErrUntrustedHeader is returned when a peer outside the trusted CIDRs sends a PROXY header.
+	ErrUntrustedHeader = errors.New("proxyproto: PROXY header from untrusted source")
+
+	// ErrMissingHeader is returned when a trusted peer does not send a PROXY header.
+	ErrMissingHeader = errors.New("proxyproto: missing PROXY header")
+
+	// ErrInvalidHeader is returned when a PROXY header is malformed.
+	ErrInvalidHeader = errors.New("proxyproto: invalid PROXY header")
+)
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+// v1MaxLength is the longest v1 header allowed by the specification, CRLF included.
+const v1MaxLength = 107
+
+// Config controls which peers may send PROXY protocol headers.
+type Config struct {
+	// TrustedCIDRs lists the networks of the load balancers allowed to send
+	// headers, e.g. "10.0.0.0/8". Connections from these networks must start
+	// with a v1 or v2 header. Connections from anywhere else are served with
+	// their socket address and rejected if they send a header.
+	TrustedCIDRs []string
+
+	// HeaderTimeout bounds how long a trusted peer may take to send its
+	// header. Defaults to DefaultHeaderTimeout. Untrusted peers are governed
+	// by the server's own timeouts instead.
+	HeaderTimeout time.Duration
+}
+
+// Listener wraps a net.Listener and decodes PROXY protocol headers, so the
+// connections it returns report the real client address from RemoteAddr.
+type Listener struct {
+	net.Listener
+	trusted []*net.IPNet
+	timeout time.Duration
+}
+
+// NewListener wraps ln with PROXY protocol support.
+func NewListener(ln net.Listener, cfg Config) (*Listener, error) {
+	trusted := make([]*net.IPNet, 0, len(cfg.TrustedCIDRs))
+	for _, cidr := range cfg.TrustedCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("proxyproto: invalid trusted CIDR %q: %w", cidr, err)
+		}
+		trusted = append(trusted, network)
+	}
+	timeout := cfg.HeaderTimeout
+	if timeout <= 0 {
+		timeout = DefaultHeaderTimeout
+	}
+	return &Listener{Listener: ln, trusted: trusted, timeout: timeout}, nil
+}
+
+// Accept returns the next connection. The header is read lazily on the first
+// call to Read, RemoteAddr or LocalAddr, so a slow peer does not block Accept.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{
+		Conn:    conn,
+		br:      bufio.NewReader(conn),
+		trusted: l.isTrusted(conn.RemoteAddr()),
+		timeout: l.timeout,
+	}, nil
+}
+
+func (l *Listener) isTrusted(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, network := range l.trusted {
+		if network.Contains(tcp.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Conn is a connection whose addresses come from its PROXY header.
+type Conn struct {
+	net.Conn
+	br      *bufio.Reader
+	trusted bool
+	timeout time.Duration
+
+	once   sync.Once
+	err    error
+	remote net.Addr
+	local  net.Addr
+}
+
+// Read reads from the connection after its header. It fails if the header
+// was rejected.
+func (c *Conn) Read(p []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.br.Read(p)
+}
+
+// RemoteAddr returns the client address from the header, or the socket
+// address when the peer did not send one. Only trusted peers are waited on.
+func (c *Conn) RemoteAddr() net.Addr {
+	if c.trusted {
+		c.once.Do(c.readHeader)
+	}
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the destination address from the header, or the socket
+// address when the peer did not send one. Only trusted peers are waited on.
+func (c *Conn) LocalAddr() net.Addr {
+	if c.trusted {
+		c.once.Do(c.readHeader)
+	}
+	if c.local != nil {
+		return c.local
+	}
+	return c.Conn.LocalAddr()
+}
+
+func (c *Conn) readHeader() {
+	if !c.trusted {
+		// Untrusted peers are not expected to send a header, so they are not
+		// held to the header timeout and read errors are left to the server's
+		// own timeouts. Only a header they do send is rejected.
+		if version, _ := c.detect(); version != 0 {
+			c.err = ErrUntrustedHeader
+			c.Conn.Close()
+		}
+		return
+	}
+
+	c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	defer c.Conn.SetReadDeadline(time.Time{})
+
+	version, err := c.detect()
+	switch {
+	case err != nil:
+		c.err = err
+	case version == 0:
+		c.err = ErrMissingHeader
+	case version == 1:
+		c.err = c.readV1()
+	case version == 2:
+		c.err = c.readV2()
+	}
+	if c.err != nil {
+		c.Conn.Close()
+	}
+}
+
+// detect peeks at the start of the stream and returns the header version,
+// or 0 when there is no header. It returns an error if the stream ended or
+// failed before a header could be told apart from other data.
+func (c *Conn) detect() (int, error) {
+	first, err := c.br.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	var signature []byte
+	switch first[0] {
+	case v1Prefix[0]:
+		signature = v1Prefix
+	case v2Signature[0]:
+		signature = v2Signature
+	default:
+		return 0, nil
+	}
+	peek, err := c.br.Peek(len(signature))
+	if !bytes.Equal(peek, signature) {
+		if err != nil && len(peek) < len(signature) {
+			return 0, err
+		}
+		return 0, nil
+	}
+	if signature[0] == v1Prefix[0] {
+		return 1, nil
+	}
+	return 2, nil
+}
+
+// readV1 parses "PROXY TCP4 <src> <dst> <sport> <dport>\r\n".
+func (c *Conn) readV1() error {
+	var line []byte
+	for len(line) < v1MaxLength {
+		b, err := c.br.ReadByte()
+		if err != nil {
+			return err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return ErrInvalidHeader
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) < 2 {
+		return ErrInvalidHeader
+	}
+	switch fields[1] {
+	case "UNKNOWN":
+		return nil
+	case "TCP4", "TCP6":
+	default:
+		return ErrInvalidHeader
+	}
+	if len(fields) != 6 {
+		return ErrInvalidHeader
+	}
+
+	src, err := parseV1Addr(fields[2], fields[4], fields[1] == "TCP4")
+	if err != nil {
+		return err
+	}
+	dst, err := parseV1Addr(fields[3], fields[5], fields[1] == "TCP4")
+	if err != nil {
+		return err
+	}
+	c.remote, c.local = src, dst
+	return nil
+}
+
+func parseV1Addr(host, port string, v4 bool) (*net.TCPAddr, error) {
+	ip := net.ParseIP(host)
+	if ip == nil || (ip.To4() != nil) != v4 {
+		return nil, ErrInvalidHeader
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || (len(port) > 1 && port[0] == '0') {
+		return nil, ErrInvalidHeader
+	}
+	return &net.TCPAddr{IP: ip, Port: int(p)}, nil
+}
+
+// readV2 parses the binary header: a 12 byte signature, version and command,
+// address family, payload length, then addresses and optional TLVs.
+func (c *Conn) readV2() error {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(c.br, header); err != nil {
+		return err
+	}
+	if header[12]>>4 != 2 {
+		return ErrInvalidHeader
+	}
+	command := header[12] & 0x0f
+	family := header[13]
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return err
+	}
+
+	switch command {
+	case 0x0: // LOCAL: health checks from the proxy itself.
+		return nil
+	case 0x1: // PROXY
+	default:
+		return ErrInvalidHeader
+	}
+
+	switch family {
+	case 0x11: // TCP over IPv4
+		if len(payload) < 12 {
+			return ErrInvalidHeader
+		}
+		c.remote = &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}
+		c.local = &net.TCPAddr{IP: net.IP(payload[4:8]), Port: int(binary.BigEndian.Uint16(payload[10:12]))}
+	case 0x21: // TCP over IPv6
+		if len(payload) < 36 {
+			return ErrInvalidHeader
+		}
+		c.remote = &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}
+		c.local = &net.TCPAddr{IP: net.IP(payload[16:32]), Port: int(binary.BigEndian.Uint16(payload[34:36]))}
+	default:
+		// Unspecified, UDP and unix sockets keep the socket addresses.
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package proxyproto
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// acceptWith dials a listener trusting cidrs, writes payload and returns the
+// accepted connection.
+func acceptWith(t *testing.T, cidrs []string, payload []byte) net.Conn {
+	t.Helper()
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { inner.Close() })
+
+	ln, err := NewListener(inner, Config{TrustedCIDRs: cidrs})
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+
+	client, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if _, err := client.Write(payload); err != nil {
+		t.Fatalf("Failed to write payload: %v", err)
+	}
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readString(t *testing.T, conn net.Conn, n int) string {
+	t.Helper()
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	return string(buf)
+}
+
+func TestV1Header(t *testing.T) {
+	conn := acceptWith(t, []string{"127.0.0.0/8"}, []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\nGET /"))
+
+	if got := conn.RemoteAddr().String(); got != "203.0.113.7:51234" {
+		t.Fatalf("Expected remote address 203.0.113.7:51234, got %s", got)
+	}
+	if got := conn.LocalAddr().String(); got != "10.0.0.1:443" {
+		t.Fatalf("Expected local address 10.0.0.1:443, got %s", got)
+	}
+	if got := readString(t, conn, 5); got != "GET /" {
+		t.Fatalf("Expected payload after header, got '%s'", got)
+	}
+}
+
+func TestV2Header(t *testing.T) {
+	header := append([]byte{}, v2Signature...)
+	header = append(header, 0x21, 0x11, 0x00, 0x0c)
+	header = append(header, 198, 51, 100, 9, 10, 0, 0, 1, 0xc8, 0x1c, 0x01, 0xbb)
+
+	conn := acceptWith(t, []string{"127.0.0.0/8"}, append(header, "PING"...))
+
+	if got := conn.RemoteAddr().String(); got != "198.51.100.9:51228" {
+		t.Fatalf("Expected remote address 198.51.100.9:51228, got %s", got)
+	}
+	if got := readString(t, conn, 4); got != "PING" {
+		t.Fatalf("Expected payload after header, got '%s'", got)
+	}
+}
+
+func TestV2LocalCommandKeepsSocketAddress(t *testing.T) {
+	header := append([]byte{}, v2Signature...)
+	header = append(header, 0x20, 0x00, 0x00, 0x00)
+
+	conn := acceptWith(t, []string{"127.0.0.0/8"}, append(header, "PING"...))
+
+	if host, _, _ := net.SplitHostPort(conn.RemoteAddr().String()); host != "127.0.0.1" {
+		t.Fatalf("Expected socket address for LOCAL command, got %s", conn.RemoteAddr())
+	}
+	if got := readString(t, conn, 4); got != "PING" {
+		t.Fatalf("Expected payload after header, got '%s'", got)
+	}
+}
+
+func TestUntrustedHeaderRejected(t *testing.T) {
+	conn := acceptWith(t, []string{"10.0.0.0/8"}, []byte("PROXY TCP4 203.0.113.7 10.0.0.1 51234 443\r\n"))
+
+	_, err := conn.Read(make([]byte, 1))
+	if !errors.Is(err, ErrUntrustedHeader) {
+		t.Fatalf("Expected ErrUntrustedHeader, got %v", err)
+	}
+}
+
+func TestUntrustedWithoutHeaderPassesThrough(t *testing.T) {
+	conn := acceptWith(t, []string{"10.0.0.0/8"}, []byte("POST / HTTP/1.1\r\n"))
+
+	if host, _, _ := net.SplitHostPort(conn.RemoteAddr().String()); host != "127.0.0.1" {
+		t.Fatalf("Expected socket address, got %s", conn.RemoteAddr())
+	}
+	if got := readString(t, conn, 6); got != "POST /" {
+		t.Fatalf("Expected untouched payload, got '%s'", got)
+	}
+}
+
+func TestIdleUntrustedPeerOutlivesHeaderTimeout(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer inner.Close()
+
+	ln, err := NewListener(inner, Config{TrustedCIDRs: []string{"10.0.0.0/8"}, HeaderTimeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to create listener: %v", err)
+	}
+
+	client, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept: %v", err)
+	}
+	defer conn.Close()
+
+	if host, _, _ := net.SplitHostPort(conn.RemoteAddr().String()); host != "127.0.0.1" {
+		t.Fatalf("Expected socket address, got %s", conn.RemoteAddr())
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		client.Write([]byte("GET /"))
+	}()
+	if got := readString(t, conn, 5); got != "GET /" {
+		t.Fatalf("Expected payload after idling, got '%s'", got)
+	}
+}
+
+func TestMalformedHeaderRejected(t *testing.T) {
+	conn := acceptWith(t, []string{"127.0.0.0/8"}, []byte("PROXY TCP4 not-an-ip 10.0.0.1 51234 443\r\n"))
+
+	_, err := conn.Read(make([]byte, 1))
+	if !errors.Is(err, ErrInvalidHeader) {
+		t.Fatalf("Expected ErrInvalidHeader, got %v", err)
+	}
+}
+
+func TestTrustedWithoutHeaderRejected(t *testing.T) {
+	conn := acceptWith(t, []string{"127.0.0.0/8"}, []byte("GET / HTTP/1.1\r\n"))
+
+	_, err := conn.Read(make([]byte, 1))
+	if !errors.Is(err, ErrMissingHeader) {
+		t.Fatalf("Expected ErrMissingHeader, got %v", err)
+	}
+}
+
+func TestInvalidTrustedCIDR(t *testing.T) {
+	if _, err := NewListener(nil, Config{TrustedCIDRs: []string{"not-a-cidr"}}); err == nil {
+		t.Fatal("Expected an error for an invalid CIDR")
+	}
+}
@@ -0,0 +1,403 @@
+package req
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describes a single input that could not be bound.
+type FieldError struct {
+	// Field is the name of the input, taken from the struct tag.
+	Field string `json:"field"`
+	// Source is where the input came from: json, form, query, param or header.
+	Source string `json:"source"`
+	// Value is the raw input, when there is one.
+	Value string `json:"value,omitempty"`
+	// Message explains what was expected.
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Source + ": " + e.Message
+	}
+	return e.Field + " (" + e.Source + "): " + e.Message
+}
+
+// BindError lists every input that could not be bound. It is answered with
+// 400 Bad Request.
+type BindError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *BindError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Error()
+	}
+	return "invalid input: " + strings.Join(messages, "; ")
+}
+
+// StatusCode returns 400 Bad Request.
+func (e *BindError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e *BindError) add(field, source, value, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Source: source, Value: value, Message: message})
+}
+
+// UnsupportedMediaTypeError is returned when a request body has a Content-Type
+// that cannot be decoded. It is answered with 415 Unsupported Media Type.
+type UnsupportedMediaTypeError struct {
+	ContentType string
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	if e.ContentType == "" {
+		return "missing Content-Type for request body"
+	}
+	return "unsupported Content-Type " + strconv.Quote(e.ContentType)
+}
+
+// StatusCode returns 415 Unsupported Media Type.
+func (e *UnsupportedMediaTypeError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+// Bind fills the struct pointed to by dst from the request. The body is
+// decoded according to its Content-Type: JSON into fields with `json` tags,
+// and URL-encoded or multipart forms into fields with `form` tags. Fields
+// tagged `query`, `param` and `header` are filled from the query string, the
+// path parameters and the request headers.
+//
+// Once bound, dst is checked against its `validate` tags (see Validate).
+//
+// Malformed input returns a *BindError listing each failing field, an
+// unsupported Content-Type returns an *UnsupportedMediaTypeError, and failed
+// rules return ValidationErrors. The error is recorded on the request, so a
+// handler that returns without writing a response answers with 400, 415 or 422.
+func (r *Request) Bind(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("req: Bind requires a non-nil pointer to a struct")
+	}
+
+	errs := &BindError{}
+	if err := r.bindBody(dst, errs); err != nil {
+		r.fail(err)
+		return err
+	}
+
+	query := r.Query()
+	bindValues(v.Elem(), "query", func(name string) []string { return query[name] }, errs)
+	bindValues(v.Elem(), "param", func(name string) []string {
+		if value := r.Req.PathValue(name); value != "" {
+			return []string{value}
+		}
+		return nil
+	}, errs)
+	bindValues(v.Elem(), "header", r.Req.Header.Values, errs)
+
+	if len(errs.Fields) > 0 {
+		r.fail(errs)
+		return errs
+	}
+	if err := Validate(dst); err != nil {
+		r.fail(err)
+		return err
+	}
+	return nil
+}
+
+// bindBody decodes the body into dst. Decoding problems are added to errs;
+// the returned error is for bodies that cannot be decoded at all.
+func (r *Request) bindBody(dst interface{}, errs *BindError) error {
+	if r.Req.Body == nil || r.Req.Body == http.NoBody {
+		return nil
+	}
+
+	contentType := r.Req.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	if mediaType == "multipart/form-data" {
+		form := r.multipart()
+		if form.err != nil {
+			return form.err
+		}
+		bindValues(reflect.ValueOf(dst).Elem(), "form", func(name string) []string { return form.values[name] }, errs)
+		return nil
+	}
+
+	data, err := r.BodyBytes()
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	if mediaType == "application/x-www-form-urlencoded" {
+		form, err := url.ParseQuery(string(data))
+		if err != nil {
+			errs.add("", "form", "", "malformed form body: "+err.Error())
+			return nil
+		}
+		bindValues(reflect.ValueOf(dst).Elem(), "form", func(name string) []string { return form[name] }, errs)
+		return nil
+	}
+
+	codec, ok := LookupCodec(mediaType)
+	if !ok {
+		return &UnsupportedMediaTypeError{ContentType: mediaType}
+	}
+	if _, ok := codec.(jsonCodec); ok {
+		decodeJSON(data, dst, errs)
+		return nil
+	}
+	decodeCodec(codec, codecSource(mediaType), data, dst, errs)
+	return nil
+}
+
+// decodeCodec decodes data with a registered codec. Values that do not fit
+// their field are reported like JSON type errors.
+func decodeCodec(codec Codec, source string, data []byte, dst interface{}, errs *BindError) {
+	err := codec.Unmarshal(data, dst)
+	if err == nil {
+		return
+	}
+	var typeErr *decodeError
+	if errors.As(err, &typeErr) {
+		errs.add(typeErr.Field, source, typeErr.Value, "must be "+describeTypeName(typeErr.Type))
+		return
+	}
+	errs.add("", source, "", "malformed "+source+": "+err.Error())
+}
+
+// decodeJSON decodes a JSON body. json.Unmarshal stores the fields that fit
+// but only reports the first type error, so the members of an object body are
+// then decoded one at a time to report every field that does not fit.
+func decodeJSON(data []byte, dst interface{}, errs *BindError) {
+	err := json.Unmarshal(data, dst)
+	if err == nil {
+		return
+	}
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		errs.add("", "json", "", "malformed JSON: "+err.Error())
+		return
+	}
+	if !jsonMemberErrors(data, reflect.TypeOf(dst).Elem(), errs) {
+		errs.add(typeErr.Field, "json", typeErr.Value, "must be "+describeType(typeErr.Type))
+	}
+}
+
+// jsonMemberErrors decodes each member of the JSON object in data into a new
+// value of type t and adds the type errors to errs. It returns false if data
+// is not an object or no member fails on its own.
+func jsonMemberErrors(data []byte, t reflect.Type, errs *BindError) bool {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return false
+	}
+
+	found := false
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return found
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return found
+		}
+		member, _ := json.Marshal(map[string]json.RawMessage{key.(string): value})
+
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(json.Unmarshal(member, reflect.New(t).Interface()), &typeErr) {
+			errs.add(typeErr.Field, "json", typeErr.Value, "must be "+describeType(typeErr.Type))
+			found = true
+		}
+	}
+	return found
+}
+
+// bindValues sets the fields of v tagged with tag from the values returned by
+// lookup. Embedded structs are bound recursively.
+func bindValues(v reflect.Value, tag string, lookup func(name string) []string, errs *BindError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, ok := field.Tag.Lookup(tag)
+		if !ok {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				bindValues(v.Field(i), tag, lookup, errs)
+			}
+			continue
+		}
+		name, _, _ = strings.Cut(name, ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		values := lookup(name)
+		if len(values) == 0 {
+			continue
+		}
+		if err := setValue(v.Field(i), values); err != nil {
+			errs.add(name, tag, values[0], err.Error())
+		}
+	}
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// setValue parses values into v. Slices take every value; other kinds take
+// the first one.
+func setValue(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), values); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(values[0])); err != nil {
+			return fmt.Errorf("must be %s", describeType(v.Type()))
+		}
+		return nil
+	}
+
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), []string{value}); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	return setScalar(v, values[0])
+}
+
+func setScalar(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.New("must be a duration")
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Slice:
+		v.SetBytes([]byte(s))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// describeType returns a human readable name for the values t accepts.
+func describeType(t reflect.Type) string {
+	if t == durationType {
+		return "a duration"
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "an RFC 3339 timestamp"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a non-negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "a valid " + t.String()
+}
+
+// describeTypeName is describeType for codecs that only report the name of
+// the Go type.
+func describeTypeName(name string) string {
+	switch {
+	case name == "time.Duration":
+		return "a duration"
+	case name == "time.Time":
+		return "an RFC 3339 timestamp"
+	case name == "string":
+		return "a string"
+	case name == "bool":
+		return "a boolean"
+	case strings.HasPrefix(name, "int"):
+		return "an integer"
+	case strings.HasPrefix(name, "uint"):
+		return "a non-negative integer"
+	case strings.HasPrefix(name, "float"):
+		return "a number"
+	case strings.HasPrefix(name, "["):
+		return "an array"
+	case strings.HasPrefix(name, "map[") || strings.HasPrefix(name, "struct"):
+		return "an object"
+	}
+	return "a valid " + name
+}
@@ -0,0 +1,143 @@
+package req
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindTarget struct {
+	Name    string        `json:"name" form:"name"`
+	Age     int           `json:"age" form:"age"`
+	Page    int           `query:"page"`
+	Tags    []string      `query:"tag"`
+	Timeout time.Duration `query:"timeout"`
+	ID      int64         `param:"id"`
+	Token   string        `header:"X-Token"`
+}
+
+func TestBindJSONQueryParamHeader(t *testing.T) {
+	httpReq := httptest.NewRequest("POST", "/users/42?page=2&tag=a&tag=b&timeout=3s", strings.NewReader(`{"name":"Gopher","age":13}`))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Token", "secret")
+	httpReq.SetPathValue("id", "42")
+
+	var dst bindTarget
+	if err := NewRequest(httpReq).Bind(&dst); err != nil {
+		t.Fatalf("Bind returned error: %v", err)
+	}
+
+	if dst.Name != "Gopher" || dst.Age != 13 {
+		t.Fatalf("Expected JSON body to be bound, got %+v", dst)
+	}
+	if dst.Page != 2 || len(dst.Tags) != 2 || dst.Tags[1] != "b" || dst.Timeout != 3*time.Second {
+		t.Fatalf("Expected query values to be bound, got %+v", dst)
+	}
+	if dst.ID != 42 || dst.Token != "secret" {
+		t.Fatalf("Expected path param and header to be bound, got %+v", dst)
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	httpReq := httptest.NewRequest("POST", "/", strings.NewReader("name=Gopher&age=13"))
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var dst bindTarget
+	if err := NewRequest(httpReq).Bind(&dst); err != nil {
+		t.Fatalf("Bind returned error: %v", err)
+	}
+	if dst.Name != "Gopher" || dst.Age != 13 {
+		t.Fatalf("Expected form body to be bound, got %+v", dst)
+	}
+}
+
+func TestBindListsFailingFields(t *testing.T) {
+	httpReq := httptest.NewRequest("GET", "/?page=two&timeout=soon", nil)
+	r := NewRequest(httpReq)
+
+	var dst bindTarget
+	err := r.Bind(&dst)
+
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		t.Fatalf("Expected *BindError, got %v", err)
+	}
+	if len(bindErr.Fields) != 2 {
+		t.Fatalf("Expected 2 field errors, got %+v", bindErr.Fields)
+	}
+	if bindErr.Fields[0].Field != "page" || bindErr.Fields[0].Source != "query" || bindErr.Fields[0].Value != "two" {
+		t.Fatalf("Unexpected field error: %+v", bindErr.Fields[0])
+	}
+	if StatusCode(r.Err()) != http.StatusBadRequest {
+		t.Fatalf("Expected recorded error to map to 400, got %d", StatusCode(r.Err()))
+	}
+}
+
+func TestBindErrorResponse(t *testing.T) {
+	r, res, rec := NewTestRequest("GET", "/?page=two", nil)
+	var dst bindTarget
+	r.Bind(&dst)
+	res.Error(r.Err())
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", rec.Code)
+	}
+	if ct := rec.Result().Header.Get("Content-Type"); ct != ProblemContentType {
+		t.Fatalf("Expected %s, got %s", ProblemContentType, ct)
+	}
+}
+
+func TestBindMalformedJSON(t *testing.T) {
+	httpReq := httptest.NewRequest("POST", "/", strings.NewReader(`{"age":"old"}`))
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	var dst bindTarget
+	err := NewRequest(httpReq).Bind(&dst)
+
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) || bindErr.Fields[0].Field != "age" || bindErr.Fields[0].Source != "json" {
+		t.Fatalf("Expected age field error from JSON, got %v", err)
+	}
+}
+
+func TestBindListsEveryJSONTypeError(t *testing.T) {
+	httpReq := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":1,"age":"old","admin":"yes","email":"gopher@example.com"}`))
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	var dst struct {
+		Name  string `json:"name"`
+		Age   int    `json:"age"`
+		Admin bool   `json:"admin"`
+		Email string `json:"email"`
+	}
+	err := NewRequest(httpReq).Bind(&dst)
+
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		t.Fatalf("Expected *BindError, got %v", err)
+	}
+	var fields []string
+	for _, f := range bindErr.Fields {
+		fields = append(fields, f.Field)
+	}
+	if strings.Join(fields, ",") != "name,age,admin" {
+		t.Fatalf("Expected errors for name, age and admin, got %+v", bindErr.Fields)
+	}
+	if dst.Email != "gopher@example.com" {
+		t.Fatalf("Expected valid fields to be bound, got %+v", dst)
+	}
+}
+
+func TestBindUnsupportedContentType(t *testing.T) {
+	httpReq := httptest.NewRequest("POST", "/", strings.NewReader("<user/>"))
+	httpReq.Header.Set("Content-Type", "application/x-unknown")
+
+	var dst bindTarget
+	err := NewRequest(httpReq).Bind(&dst)
+	if StatusCode(err) != http.StatusUnsupportedMediaType {
+		t.Fatalf("Expected 415 error, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package req
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// DefaultMaxBodySize is the default limit, in bytes, on request bodies.
+const DefaultMaxBodySize int64 = 10 << 20
+
+// bufferedBody is a request body that has been read into memory. It can be
+// rewound, so middleware and handlers can all read the same body.
+type bufferedBody struct {
+	*bytes.Reader
+	data []byte
+}
+
+func (b *bufferedBody) Close() error {
+	return nil
+}
+
+// failedBody replays the error that happened while buffering a body.
+type failedBody struct {
+	err error
+}
+
+func (b failedBody) Read(p []byte) (int, error) {
+	return 0, b.err
+}
+
+func (b failedBody) Close() error {
+	return nil
+}
+
+// BufferBody reads the body of r into memory and replaces r.Body with a
+// rewindable copy, so it can be read again by middleware and handlers.
+// Calling it again returns the same bytes and rewinds r.Body to the start.
+// When the App limits the body size, reading past the limit returns an
+// *http.MaxBytesError.
+func BufferBody(r *http.Request) ([]byte, error) {
+	switch body := r.Body.(type) {
+	case nil:
+		return nil, nil
+	case *bufferedBody:
+		body.Reset(body.data)
+		return body.data, nil
+	case failedBody:
+		return nil, body.err
+	}
+	if r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		r.Body = failedBody{err: err}
+		return nil, err
+	}
+	r.Body = &bufferedBody{Reader: bytes.NewReader(data), data: data}
+	return data, nil
+}
@@ -0,0 +1,189 @@
+package req
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BrunoCiccarino/GopherLight/logger"
+)
+
+// DefaultBodyLogMaxSize is the number of body bytes logged when MaxSize is not set.
+const DefaultBodyLogMaxSize = 1024
+
+// redacted replaces the values of redacted fields.
+const redacted = "[REDACTED]"
+
+// DefaultBodyLogContentTypes are the media types logged when ContentTypes is empty.
+var DefaultBodyLogContentTypes = []string{"application/json", "application/x-www-form-urlencoded", "text/*"}
+
+// BodyLogPolicy controls whether request bodies are logged and how.
+type BodyLogPolicy struct {
+	// Enabled turns body logging on.
+	Enabled bool
+
+	// MaxSize caps the number of bytes logged. Longer bodies are truncated.
+	// Defaults to DefaultBodyLogMaxSize.
+	MaxSize int
+
+	// ContentTypes lists the media types to log, such as "application/json"
+	// or "text/*". Defaults to DefaultBodyLogContentTypes.
+	ContentTypes []string
+
+	// Redact lists the JSON and form fields whose values are replaced before
+	// logging. A plain name such as "password" matches the field at any depth;
+	// a dotted path such as "*.token" matches from the root, with "*" standing
+	// for any single field name. Matching is case-insensitive.
+	Redact []string
+}
+
+// LogBody returns a handler that logs request bodies with policy, overriding
+// the App's policy for a single route.
+func LogBody(policy BodyLogPolicy, handler Handler) Handler {
+	return func(r *Request, w *Response) {
+		r.bodyLog = &policy
+		handler(r, w)
+	}
+}
+
+// logBody logs data according to the route or application policy.
+func (r *Request) logBody(data []byte) {
+	policy := r.bodyLog
+	if policy == nil {
+		policy = &ConfigFromContext(r.Req.Context()).BodyLog
+	}
+	if !policy.Enabled {
+		return
+	}
+
+	contentType := r.Req.Header.Get("Content-Type")
+	if !policy.logsContentType(contentType) {
+		return
+	}
+
+	logger.LogInfo("Received request body: " + policy.format(contentType, data))
+}
+
+func (p *BodyLogPolicy) logsContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	types := p.ContentTypes
+	if len(types) == 0 {
+		types = DefaultBodyLogContentTypes
+	}
+	for _, t := range types {
+		if MatchMediaType(t, mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// format redacts and truncates data for logging.
+func (p *BodyLogPolicy) format(contentType string, data []byte) string {
+	if len(p.Redact) > 0 {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		switch {
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+			data = p.redactJSON(data)
+		case mediaType == "application/x-www-form-urlencoded":
+			data = p.redactForm(data)
+		}
+	}
+
+	maxSize := p.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultBodyLogMaxSize
+	}
+	if len(data) > maxSize {
+		// Back off to a rune boundary so the log line stays valid UTF-8.
+		cut := maxSize
+		for cut > 0 && !utf8.RuneStart(data[cut]) {
+			cut--
+		}
+		return string(data[:cut]) + "... (" + strconv.Itoa(len(data)-cut) + " more bytes)"
+	}
+	return string(data)
+}
+
+func (p *BodyLogPolicy) redactJSON(data []byte) []byte {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return []byte("[unparsable JSON body omitted]")
+	}
+	value = p.redactValue(nil, value)
+	out, err := json.Marshal(value)
+	if err != nil {
+		return []byte("[unparsable JSON body omitted]")
+	}
+	return out
+}
+
+func (p *BodyLogPolicy) redactValue(path []string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			childPath := append(path[:len(path):len(path)], key)
+			if p.redacts(childPath) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = p.redactValue(childPath, child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = p.redactValue(path, child)
+		}
+	}
+	return value
+}
+
+func (p *BodyLogPolicy) redactForm(data []byte) []byte {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return []byte("[unparsable form body omitted]")
+	}
+	for key, vs := range values {
+		if p.redacts([]string{key}) {
+			for i := range vs {
+				vs[i] = redacted
+			}
+		}
+	}
+	return []byte(values.Encode())
+}
+
+// redacts reports whether the field at path matches a redaction rule.
+func (p *BodyLogPolicy) redacts(path []string) bool {
+	for _, rule := range p.Redact {
+		segments := strings.Split(rule, ".")
+		if len(segments) == 1 {
+			if segments[0] == "*" || strings.EqualFold(segments[0], path[len(path)-1]) {
+				return true
+			}
+			continue
+		}
+		if len(segments) != len(path) {
+			continue
+		}
+		matched := true
+		for i, segment := range segments {
+			if segment != "*" && !strings.EqualFold(segment, path[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
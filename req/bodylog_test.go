@@ -0,0 +1,85 @@
+package req
+
+import (
+	"bytes"
+	"log"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func captureLog(f func()) string {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	f()
+	return buf.String()
+}
+
+func readWithPolicy(policy BodyLogPolicy, contentType, body string) string {
+	httpReq := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	httpReq.Header.Set("Content-Type", contentType)
+	r := NewRequest(httpReq)
+	return captureLog(func() {
+		LogBody(policy, func(r *Request, w *Response) {
+			r.BodyAsString()
+		})(r, nil)
+	})
+}
+
+func TestBodyNotLoggedByDefault(t *testing.T) {
+	httpReq := httptest.NewRequest("POST", "/", strings.NewReader(`{"password":"hunter2"}`))
+	httpReq.Header.Set("Content-Type", "application/json")
+	r := NewRequest(httpReq)
+
+	output := captureLog(func() { r.BodyAsString() })
+	if strings.Contains(output, "hunter2") {
+		t.Fatalf("Expected body not to be logged, got: %s", output)
+	}
+}
+
+func TestBodyLogRedactsFields(t *testing.T) {
+	policy := BodyLogPolicy{Enabled: true, Redact: []string{"password", "*.token"}}
+	output := readWithPolicy(policy, "application/json",
+		`{"user":"gopher","password":"hunter2","session":{"token":"abc","id":7},"token":"top-level"}`)
+
+	for _, secret := range []string{"hunter2", `"abc"`} {
+		if strings.Contains(output, secret) {
+			t.Fatalf("Expected %s to be redacted, got: %s", secret, output)
+		}
+	}
+	for _, kept := range []string{"gopher", "top-level", `"id":7`} {
+		if !strings.Contains(output, kept) {
+			t.Fatalf("Expected %s to be logged, got: %s", kept, output)
+		}
+	}
+}
+
+func TestBodyLogRedactsFormFields(t *testing.T) {
+	policy := BodyLogPolicy{Enabled: true, Redact: []string{"password"}}
+	output := readWithPolicy(policy, "application/x-www-form-urlencoded", "user=gopher&password=hunter2")
+
+	if strings.Contains(output, "hunter2") || !strings.Contains(output, "user=gopher") {
+		t.Fatalf("Expected only the password to be redacted, got: %s", output)
+	}
+}
+
+func TestBodyLogContentTypeFilterAndSizeCap(t *testing.T) {
+	policy := BodyLogPolicy{Enabled: true, MaxSize: 4, ContentTypes: []string{"text/*"}}
+
+	if output := readWithPolicy(policy, "application/octet-stream", "binary"); strings.Contains(output, "Received request body") {
+		t.Fatalf("Expected octet-stream body not to be logged, got: %s", output)
+	}
+
+	output := readWithPolicy(policy, "text/plain; charset=utf-8", "truncated")
+	if !strings.Contains(output, "Received request body: trun... (5 more bytes)") {
+		t.Fatalf("Expected truncated body in log, got: %s", output)
+	}
+
+	// "né" is 3 bytes, so a 4-byte cap would split the second "é".
+	output = readWithPolicy(policy, "text/plain; charset=utf-8", "néé")
+	if !strings.Contains(output, "Received request body: né... (2 more bytes)") {
+		t.Fatalf("Expected truncation on a rune boundary, got: %s", output)
+	}
+}
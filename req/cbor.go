@@ -0,0 +1,52 @@
+package req
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// cborCodec implements CBOR (RFC 8949). Struct fields are named by their
+// `cbor` tag, falling back to the `json` tag. time.Time is written as an
+// RFC 3339 string with tag 0; epoch timestamps (tag 1) are also decoded.
+type cborCodec struct{}
+
+var (
+	cborEnc = mustCBOR(cbor.EncOptions{
+		Time:    cbor.TimeRFC3339Nano,
+		TimeTag: cbor.EncTagRequired,
+	}.EncMode())
+
+	// Maps decode into interface{} values as map[string]interface{}, like the
+	// other codecs, and integers as int64 unless they do not fit.
+	cborDec = mustCBOR(cbor.DecOptions{
+		MaxNestedLevels: maxCodecDepth,
+		DefaultMapType:  reflect.TypeOf(map[string]interface{}(nil)),
+		IntDec:          cbor.IntDecConvertSignedOrBigInt,
+	}.DecMode())
+)
+
+func mustCBOR[T any](mode T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}
+
+func (cborCodec) Marshal(v interface{}) ([]byte, error) { return cborEnc.Marshal(v) }
+
+func (cborCodec) Unmarshal(data []byte, v interface{}) error {
+	err := cborDec.Unmarshal(data, v)
+	var typeErr *cbor.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		// StructFieldName is the Go struct type followed by the field name.
+		field := typeErr.StructFieldName
+		if i := strings.LastIndexByte(field, '.'); i >= 0 {
+			field = field[i+1:]
+		}
+		return &decodeError{Field: field, Value: typeErr.CBORType, Type: typeErr.GoType, err: err}
+	}
+	return err
+}
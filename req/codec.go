@@ -0,0 +1,163 @@
+package req
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/BrunoCiccarino/GopherLight/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// Codec encodes and decodes values for one media type. Bind uses it to
+// decode request bodies and Response.Encode to render responses.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// maxCodecDepth bounds the nesting of decoded MessagePack and CBOR values.
+const maxCodecDepth = 512
+
+// codecs is the registry behind RegisterCodec. order keeps registration order,
+// which is the server preference used by Response.Negotiate.
+var codecs = struct {
+	sync.RWMutex
+	byType map[string]Codec
+	order  []string
+}{byType: map[string]Codec{}}
+
+func init() {
+	RegisterCodec("application/json", jsonCodec{})
+	RegisterCodec("application/xml", xmlCodec{})
+	RegisterCodec("application/yaml", yamlCodec{})
+	RegisterCodec("application/msgpack", msgpackCodec{})
+	RegisterCodec("application/cbor", cborCodec{})
+	RegisterCodec("text/xml", xmlCodec{})
+	RegisterCodec("application/x-yaml", yamlCodec{})
+	RegisterCodec("text/yaml", yamlCodec{})
+	RegisterCodec("application/x-msgpack", msgpackCodec{})
+	RegisterCodec("application/vnd.msgpack", msgpackCodec{})
+}
+
+// RegisterCodec makes codec available for mediaType, replacing any codec
+// already registered for it. Register codecs before serving requests.
+func RegisterCodec(mediaType string, codec Codec) {
+	mediaType = strings.ToLower(mediaType)
+	codecs.Lock()
+	defer codecs.Unlock()
+	if _, ok := codecs.byType[mediaType]; !ok {
+		codecs.order = append(codecs.order, mediaType)
+	}
+	codecs.byType[mediaType] = codec
+}
+
+// LookupCodec returns the codec for mediaType. Types with a structured syntax
+// suffix, such as "application/problem+json", fall back to the codec of the
+// suffix ("application/json").
+func LookupCodec(mediaType string) (Codec, bool) {
+	mediaType = strings.ToLower(mediaType)
+	codecs.RLock()
+	defer codecs.RUnlock()
+	if codec, ok := codecs.byType[mediaType]; ok {
+		return codec, true
+	}
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		codec, ok := codecs.byType["application/"+mediaType[i+1:]]
+		return codec, ok
+	}
+	return nil, false
+}
+
+// CodecTypes returns the registered media types in registration order.
+func CodecTypes() []string {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return append([]string(nil), codecs.order...)
+}
+
+// decodeError reports a decoded value that does not fit its destination.
+// Codecs return it so Bind can report the field like a JSON type error.
+type decodeError struct {
+	Field string // The name of the field.
+	Value string // The kind of value that was decoded.
+	Type  string // The name of the Go type of the field.
+	err   error
+}
+
+func (e *decodeError) Error() string { return e.err.Error() }
+func (e *decodeError) Unwrap() error { return e.err }
+
+// codecSource names the input source of a media type in a FieldError, such
+// as "xml" for "text/xml" or "msgpack" for "application/vnd.msgpack".
+func codecSource(mediaType string) string {
+	_, subtype, _ := strings.Cut(mediaType, "/")
+	if i := strings.LastIndexByte(subtype, '+'); i >= 0 {
+		subtype = subtype[i+1:]
+	}
+	subtype = strings.TrimPrefix(subtype, "x-")
+	return strings.TrimPrefix(subtype, "vnd.")
+}
+
+// Encode serializes data with the codec registered for mediaType and sends
+// it with a 200 status unless another status was already set.
+func (res *Response) Encode(mediaType string, data interface{}) {
+	codec, ok := LookupCodec(mediaType)
+	if !ok {
+		logger.LogError("No codec registered for " + mediaType)
+		res.Problem(NewProblem(http.StatusInternalServerError, "error encoding response"))
+		return
+	}
+	body, err := codec.Marshal(data)
+	if err != nil {
+		name := strings.ToUpper(codecSource(mediaType))
+		logger.LogError("Error encoding " + name + " response: " + err.Error())
+		res.Problem(NewProblem(http.StatusInternalServerError, "error encoding "+name))
+		return
+	}
+	res.Header().Set("Content-Type", mediaType)
+	res.writeStatusIfNotWritten(http.StatusOK)
+	res.Write(body)
+}
+
+// Negotiate sends data in the registered media type the client prefers
+// according to its Accept header, or answers 406 Not Acceptable. Without an
+// Accept header, JSON is sent.
+func (res *Response) Negotiate(data interface{}) {
+	res.Header().Add("Vary", "Accept")
+	var accept []string
+	if res.req != nil {
+		accept = res.req.Header.Values("Accept")
+	}
+	offers := CodecTypes()
+	mediaType := negotiate(accept, offers)
+	if mediaType == "" {
+		res.Error(&NotAcceptableError{Offers: offers})
+		return
+	}
+	res.Encode(mediaType, data)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type xmlCodec struct{}
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error { return xml.Unmarshal(data, v) }
+
+type yamlCodec struct{}
+
+func (yamlCodec) Marshal(v interface{}) ([]byte, error)      { return yaml.Marshal(v) }
+func (yamlCodec) Unmarshal(data []byte, v interface{}) error { return yaml.Unmarshal(data, v) }
@@ -0,0 +1,326 @@
+package req
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type codecAddress struct {
+	City string `json:"city"`
+}
+
+type codecSample struct {
+	Name     string            `json:"name"`
+	Age      int               `json:"age"`
+	Score    float64           `json:"score"`
+	Active   bool              `json:"active"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Raw      []byte            `json:"raw"`
+	Home     *codecAddress     `json:"home"`
+	Created  time.Time         `json:"created"`
+	Skipped  string            `json:"-"`
+	Optional string            `json:"optional,omitempty"`
+	Big      uint64            `json:"big"`
+	Negative int64             `json:"negative"`
+}
+
+func TestBinaryCodecsRoundTrip(t *testing.T) {
+	in := codecSample{
+		Name:     "Gopher",
+		Age:      13,
+		Score:    9.5,
+		Active:   true,
+		Tags:     []string{"a", "b"},
+		Labels:   map[string]string{"team": "go"},
+		Raw:      []byte{0, 1, 2},
+		Home:     &codecAddress{City: "Lisbon"},
+		Created:  time.Date(2024, 5, 1, 12, 30, 0, 500, time.UTC),
+		Skipped:  "not encoded",
+		Big:      math.MaxUint64,
+		Negative: -100000,
+	}
+
+	for _, mediaType := range []string{"application/msgpack", "application/cbor"} {
+		t.Run(mediaType, func(t *testing.T) {
+			codec, _ := LookupCodec(mediaType)
+			data, err := codec.Marshal(in)
+			if err != nil {
+				t.Fatalf("Marshal returned error: %v", err)
+			}
+
+			var out codecSample
+			if err := codec.Unmarshal(data, &out); err != nil {
+				t.Fatalf("Unmarshal returned error: %v", err)
+			}
+			// Timestamps decode in the local time zone.
+			if !out.Created.Equal(in.Created) {
+				t.Fatalf("Expected created %v, got %v", in.Created, out.Created)
+			}
+			want := in
+			want.Skipped = ""
+			want.Created = out.Created
+			if !reflect.DeepEqual(out, want) {
+				t.Fatalf("Round trip mismatch:\n got %+v\nwant %+v", out, want)
+			}
+
+			var generic map[string]interface{}
+			if err := codec.Unmarshal(data, &generic); err != nil {
+				t.Fatalf("Unmarshal into a map returned error: %v", err)
+			}
+			if generic["name"] != "Gopher" || generic["age"] != int64(13) {
+				t.Fatalf("Unexpected generic value: %v", generic)
+			}
+		})
+	}
+}
+
+func TestBinaryCodecsKnownEncodings(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		value     interface{}
+		hex       string
+	}{
+		{"application/msgpack", map[string]int{"a": 1}, "81a16101"},
+		{"application/msgpack", []int{-1, -33, 256}, "93ffd0dfcd0100"},
+		{"application/msgpack", time.Unix(1, 0).UTC(), "d6ff00000001"},
+		{"application/cbor", []interface{}{1, []int{2, 3}}, "8201820203"},
+		{"application/cbor", -1000, "3903e7"},
+		{"application/cbor", "IETF", "6449455446"},
+		{"application/cbor", map[string]bool{"a": true}, "a16161f5"},
+	}
+
+	for _, tt := range tests {
+		codec, _ := LookupCodec(tt.mediaType)
+		data, err := codec.Marshal(tt.value)
+		if err != nil {
+			t.Fatalf("%s: Marshal(%v) returned error: %v", tt.mediaType, tt.value, err)
+		}
+		if got := hex.EncodeToString(data); got != tt.hex {
+			t.Errorf("%s: Marshal(%v) = %s, want %s", tt.mediaType, tt.value, got, tt.hex)
+		}
+	}
+}
+
+func TestCBORDecodesRFCExamples(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want interface{}
+	}{
+		{"1903e8", int64(1000)},
+		{"f93c00", 1.0},
+		{"f9c400", -4.0},
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"9f018202039f0405ffff", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}},
+		{"c11a514b67b0", time.Unix(1363896240, 0).UTC()},
+		{"f6", nil},
+	}
+
+	for _, tt := range tests {
+		data, _ := hex.DecodeString(tt.hex)
+		var got interface{}
+		if err := (cborCodec{}).Unmarshal(data, &got); err != nil {
+			t.Errorf("%s: Unmarshal returned error: %v", tt.hex, err)
+			continue
+		}
+		if tm, ok := tt.want.(time.Time); ok {
+			if !tm.Equal(got.(time.Time)) {
+				t.Errorf("%s: got %v, want %v", tt.hex, got, tt.want)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %#v, want %#v", tt.hex, got, tt.want)
+		}
+	}
+}
+
+func TestBinaryCodecsRejectMalformedInput(t *testing.T) {
+	tests := []struct {
+		mediaType string
+		hex       string
+	}{
+		{"application/msgpack", "dc"},
+		{"application/msgpack", "ddffffffff"},
+		{"application/msgpack", "c1"},
+		{"application/msgpack", "0101"},
+		{"application/cbor", "9b00000000ffffffff"},
+		{"application/cbor", "5f"},
+		{"application/cbor", "62c328"},
+		{"application/cbor", "ff"},
+	}
+
+	for _, tt := range tests {
+		codec, _ := LookupCodec(tt.mediaType)
+		data, _ := hex.DecodeString(tt.hex)
+		var v interface{}
+		if err := codec.Unmarshal(data, &v); err == nil {
+			t.Errorf("%s %s: expected an error", tt.mediaType, tt.hex)
+		}
+	}
+}
+
+func TestBindWithCodecs(t *testing.T) {
+	msgpackBody, _ := (msgpackCodec{}).Marshal(map[string]interface{}{"name": "Gopher", "age": 13})
+	cborBody, _ := (cborCodec{}).Marshal(map[string]interface{}{"name": "Gopher", "age": 13})
+
+	tests := []struct {
+		contentType string
+		body        []byte
+	}{
+		{"application/msgpack", msgpackBody},
+		{"application/cbor", cborBody},
+		{"application/yaml", []byte("name: Gopher\nage: 13\n")},
+		{"application/xml", []byte("<bindTarget><Name>Gopher</Name><Age>13</Age></bindTarget>")},
+	}
+
+	for _, tt := range tests {
+		r, _, _ := NewTestRequest("POST", "/", bytes.NewReader(tt.body))
+		r.Req.Header.Set("Content-Type", tt.contentType)
+
+		var dst bindTarget
+		if err := r.Bind(&dst); err != nil {
+			t.Fatalf("%s: Bind returned error: %v", tt.contentType, err)
+		}
+		if dst.Name != "Gopher" || dst.Age != 13 {
+			t.Fatalf("%s: expected body to be bound, got %+v", tt.contentType, dst)
+		}
+	}
+}
+
+func TestBindCodecTypeError(t *testing.T) {
+	bind := func(codec Codec, contentType string) FieldError {
+		body, _ := codec.Marshal(map[string]interface{}{"age": "old"})
+		r, _, _ := NewTestRequest("POST", "/", bytes.NewReader(body))
+		r.Req.Header.Set("Content-Type", contentType)
+
+		var dst bindTarget
+		err := r.Bind(&dst)
+		bindErr, ok := err.(*BindError)
+		if !ok {
+			t.Fatalf("%s: expected *BindError, got %v", contentType, err)
+		}
+		return bindErr.Fields[0]
+	}
+
+	if f := bind(cborCodec{}, "application/cbor"); f.Field != "age" || f.Source != "cbor" || f.Message != "must be an integer" {
+		t.Fatalf("Unexpected CBOR field error: %+v", f)
+	}
+	// MessagePack errors do not name the field.
+	if f := bind(msgpackCodec{}, "application/msgpack"); f.Source != "msgpack" || !strings.HasPrefix(f.Message, "malformed msgpack") {
+		t.Fatalf("Unexpected MessagePack error: %+v", f)
+	}
+}
+
+func TestMsgpackRejectsHostileLengths(t *testing.T) {
+	tests := map[string][]byte{
+		"huge array": {0xdd, 0xff, 0xff, 0xff, 0xff},
+		"huge map":   {0xdf, 0xff, 0xff, 0xff, 0xff},
+		"deep array": bytes.Repeat([]byte{0x91}, maxCodecDepth+2),
+	}
+
+	for name, data := range tests {
+		var v interface{}
+		if err := (msgpackCodec{}).Unmarshal(data, &v); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+type upperCodec struct{}
+
+func (upperCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(strings.ToUpper(v.(string))), nil
+}
+
+func (upperCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*string) = strings.ToLower(string(data))
+	return nil
+}
+
+func TestNegotiateUsesRegisteredCodecs(t *testing.T) {
+	RegisterCodec("text/x-upper", upperCodec{})
+
+	respond := func(accept string) (int, string, string) {
+		r, res, rec := NewTestRequest("GET", "/", nil)
+		r.Req.Header.Set("Accept", accept)
+		res.Negotiate("hello")
+		return rec.Code, rec.Header().Get("Content-Type"), rec.Body.String()
+	}
+
+	if _, contentType, body := respond("text/x-upper"); contentType != "text/x-upper" || body != "HELLO" {
+		t.Fatalf("Expected custom codec output, got %s '%s'", contentType, body)
+	}
+	if _, contentType, _ := respond("application/msgpack, application/json;q=0.5"); contentType != "application/msgpack" {
+		t.Fatalf("Expected MessagePack, got %s", contentType)
+	}
+	if _, contentType, _ := respond("*/*"); contentType != "application/json" {
+		t.Fatalf("Expected JSON for */*, got %s", contentType)
+	}
+	if status, _, _ := respond("image/png"); status != http.StatusNotAcceptable {
+		t.Fatalf("Expected 406, got %d", status)
+	}
+}
+
+// fuzzCodec decodes data into the kinds of destinations handlers bind to.
+// Errors are expected for most inputs; only panics fail.
+func fuzzCodec(t *testing.T, codec Codec, data []byte) {
+	var generic interface{}
+	codec.Unmarshal(data, &generic)
+
+	var sample codecSample
+	codec.Unmarshal(data, &sample)
+
+	var nested struct {
+		Meta interface{} `json:"meta"`
+		Tags []string    `json:"tags"`
+	}
+	codec.Unmarshal(data, &nested)
+}
+
+func codecSeeds(f *testing.F, codec Codec, hexSeeds ...string) {
+	sample, _ := codec.Marshal(codecSample{
+		Name:    "Gopher",
+		Tags:    []string{"a"},
+		Labels:  map[string]string{"k": "v"},
+		Raw:     []byte{1},
+		Home:    &codecAddress{City: "Lisbon"},
+		Created: time.Unix(1, 0).UTC(),
+	})
+	f.Add(sample)
+	for _, s := range hexSeeds {
+		data, _ := hex.DecodeString(s)
+		f.Add(data)
+	}
+}
+
+func FuzzMsgpack(f *testing.F) {
+	codecSeeds(f, msgpackCodec{},
+		"81a16101", "93ffd0dfcd0100", "d6ff00000001",
+		"dc", "ddffffffff", "c1", "0101",
+		// A map with a nil key next to an integer key.
+		"81a46d65746182c0010102",
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzCodec(t, msgpackCodec{}, data)
+	})
+}
+
+func FuzzCBOR(f *testing.F) {
+	codecSeeds(f, cborCodec{},
+		"8201820203", "a16161f5", "5f42010243030405ff", "9f018202039f0405ffff", "c11a514b67b0",
+		"9b00000000ffffffff", "5f", "62c328", "ff",
+		// A map with a null key next to an integer key.
+		"a1646d657461a2f6010102",
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzzCodec(t, cborCodec{}, data)
+	})
+}
@@ -0,0 +1,45 @@
+package req
+
+import "context"
+
+// Config holds the application-wide settings used while handling requests.
+// The App stores it in each request's context.
+type Config struct {
+	// BodyLog controls request body logging. It is disabled by default.
+	BodyLog BodyLogPolicy
+
+	// Uploads limits multipart file uploads.
+	Uploads UploadConfig
+
+	// Cookies signs and encrypts cookie values. Nil until keys are set.
+	Cookies *Keyring
+
+	// Errors answers errors returned by handlers. Nil means
+	// DefaultErrorHandler.
+	Errors ErrorHandler
+
+	// Shutdown is closed when the server handling the request starts
+	// shutting down, so long-lived responses such as event streams can end.
+	Shutdown <-chan struct{}
+
+	// Views renders the templates used by Response.Render.
+	Views Renderer
+}
+
+type configKey struct{}
+
+var defaultConfig Config
+
+// ContextWithConfig returns a copy of ctx carrying cfg.
+func ContextWithConfig(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, configKey{}, cfg)
+}
+
+// ConfigFromContext returns the Config stored in ctx, or the defaults when
+// the request is not served by an App.
+func ConfigFromContext(ctx context.Context) *Config {
+	if cfg, ok := ctx.Value(configKey{}).(*Config); ok {
+		return cfg
+	}
+	return &defaultConfig
+}
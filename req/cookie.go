@@ -0,0 +1,274 @@
+package req
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MinCookieKeyLen is the minimum length of a cookie secret.
+const MinCookieKeyLen = 32
+
+var (
+	// ErrInvalidCookie is returned when a signed or encrypted cookie was
+	// tampered with or was not issued by any key in the keyring.
+	ErrInvalidCookie = errors.New("req: invalid cookie")
+
+	// ErrNoCookieKeys is returned when signed or encrypted cookies are used
+	// before the app was given any keys.
+	ErrNoCookieKeys = errors.New("req: no cookie keys configured")
+)
+
+// CookieOptions changes the attributes of a cookie. The zero value gives
+// secure defaults: Path "/", HttpOnly, Secure and SameSite=Lax.
+type CookieOptions struct {
+	Path   string
+	Domain string
+	// MaxAge is the lifetime in seconds. Zero makes a session cookie.
+	MaxAge int
+	// Expires is an absolute expiry, for clients that ignore MaxAge.
+	Expires time.Time
+	// SameSite defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+	// Insecure drops the Secure attribute, for plain HTTP during development.
+	Insecure bool
+	// AllowScript drops the HttpOnly attribute so JavaScript can read the cookie.
+	AllowScript bool
+}
+
+func (o CookieOptions) cookie(name, value string) *http.Cookie {
+	c := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		MaxAge:   o.MaxAge,
+		Expires:  o.Expires,
+		SameSite: o.SameSite,
+		Secure:   !o.Insecure,
+		HttpOnly: !o.AllowScript,
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	if c.SameSite == 0 {
+		c.SameSite = http.SameSiteLaxMode
+	}
+	return c
+}
+
+// cookieKey holds the keys derived from one secret.
+type cookieKey struct {
+	sign []byte
+	aead cipher.AEAD
+}
+
+// Keyring signs and encrypts cookie values. The first key is used for new
+// cookies; the others only verify and decrypt existing ones, so keys can be
+// rotated by adding a new secret in front of the old ones.
+type Keyring struct {
+	keys []cookieKey
+}
+
+// NewKeyring returns a Keyring for secrets, newest first. Each secret must be
+// at least MinCookieKeyLen bytes of random data.
+func NewKeyring(secrets ...[]byte) (*Keyring, error) {
+	if len(secrets) == 0 {
+		return nil, ErrNoCookieKeys
+	}
+	k := &Keyring{}
+	for _, secret := range secrets {
+		if len(secret) < MinCookieKeyLen {
+			return nil, errors.New("req: cookie keys must be at least 32 bytes")
+		}
+		block, err := aes.NewCipher(deriveKey(secret, "gopherlight cookie encryption"))
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.keys = append(k.keys, cookieKey{sign: deriveKey(secret, "gopherlight cookie signing"), aead: aead})
+	}
+	return k, nil
+}
+
+// deriveKey gives signing and encryption separate keys from one secret.
+func deriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// Sign returns value with an HMAC-SHA256 signature bound to the cookie name.
+// It returns ErrNoCookieKeys if the keyring holds no keys.
+func (k *Keyring) Sign(name, value string) (string, error) {
+	if len(k.keys) == 0 {
+		return "", ErrNoCookieKeys
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(value))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(k.keys[0].mac(name, payload)), nil
+}
+
+// Verify checks a value produced by Sign with any key and returns the
+// original value.
+func (k *Keyring) Verify(name, signed string) (string, error) {
+	payload, sig, ok := strings.Cut(signed, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	want, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, key := range k.keys {
+		if hmac.Equal(want, key.mac(name, payload)) {
+			value, err := base64.RawURLEncoding.DecodeString(payload)
+			if err != nil {
+				return "", ErrInvalidCookie
+			}
+			return string(value), nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+func (key cookieKey) mac(name, payload string) []byte {
+	mac := hmac.New(sha256.New, key.sign)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// Encrypt seals value with AES-256-GCM, using the cookie name as additional
+// data so a value cannot be moved to another cookie. It returns
+// ErrNoCookieKeys if the keyring holds no keys.
+func (k *Keyring) Encrypt(name, value string) (string, error) {
+	if len(k.keys) == 0 {
+		return "", ErrNoCookieKeys
+	}
+	aead := k.keys[0].aead
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt with any key.
+func (k *Keyring) Decrypt(name, encrypted string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	for _, key := range k.keys {
+		size := key.aead.NonceSize()
+		if len(data) < size {
+			continue
+		}
+		if value, err := key.aead.Open(nil, data[:size], data[size:], []byte(name)); err == nil {
+			return string(value), nil
+		}
+	}
+	return "", ErrInvalidCookie
+}
+
+// Cookie returns the value of the named cookie, or http.ErrNoCookie.
+func (r *Request) Cookie(name string) (string, error) {
+	c, err := r.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return c.Value, nil
+}
+
+// SignedCookie returns the value of a cookie set with SetSignedCookie. It
+// returns ErrInvalidCookie if the signature does not match any key.
+func (r *Request) SignedCookie(name string) (string, error) {
+	keys, value, err := r.keyedCookie(name)
+	if err != nil {
+		return "", err
+	}
+	return keys.Verify(name, value)
+}
+
+// EncryptedCookie returns the value of a cookie set with SetEncryptedCookie.
+// It returns ErrInvalidCookie if no key can decrypt it.
+func (r *Request) EncryptedCookie(name string) (string, error) {
+	keys, value, err := r.keyedCookie(name)
+	if err != nil {
+		return "", err
+	}
+	return keys.Decrypt(name, value)
+}
+
+func (r *Request) keyedCookie(name string) (*Keyring, string, error) {
+	keys := ConfigFromContext(r.Req.Context()).Cookies
+	if keys == nil {
+		return nil, "", ErrNoCookieKeys
+	}
+	value, err := r.Cookie(name)
+	return keys, value, err
+}
+
+// SetCookie adds a Set-Cookie header. Attributes come from opts, which
+// defaults to secure settings (see CookieOptions).
+func (res *Response) SetCookie(name, value string, opts ...CookieOptions) {
+	http.SetCookie(res, cookieOptions(opts).cookie(name, value))
+}
+
+// SetSignedCookie sets a cookie whose value is readable by the client but
+// cannot be changed without the app's keys.
+func (res *Response) SetSignedCookie(name, value string, opts ...CookieOptions) error {
+	keys := res.config().Cookies
+	if keys == nil {
+		return ErrNoCookieKeys
+	}
+	signed, err := keys.Sign(name, value)
+	if err != nil {
+		return err
+	}
+	res.SetCookie(name, signed, opts...)
+	return nil
+}
+
+// SetEncryptedCookie sets a cookie whose value the client can neither read
+// nor change.
+func (res *Response) SetEncryptedCookie(name, value string, opts ...CookieOptions) error {
+	keys := res.config().Cookies
+	if keys == nil {
+		return ErrNoCookieKeys
+	}
+	sealed, err := keys.Encrypt(name, value)
+	if err != nil {
+		return err
+	}
+	res.SetCookie(name, sealed, opts...)
+	return nil
+}
+
+// ClearCookie tells the client to delete a cookie. Path and Domain in opts
+// must match the ones the cookie was set with.
+func (res *Response) ClearCookie(name string, opts ...CookieOptions) {
+	c := cookieOptions(opts).cookie(name, "")
+	c.MaxAge = -1
+	c.Expires = time.Unix(0, 0)
+	http.SetCookie(res, c)
+}
+
+func cookieOptions(opts []CookieOptions) CookieOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return CookieOptions{}
+}
@@ -0,0 +1,130 @@
+package req
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var (
+	oldCookieKey = bytes.Repeat([]byte("o"), 32)
+	newCookieKey = bytes.Repeat([]byte("n"), 32)
+)
+
+// cookieRoundTrip sets a cookie with set using keys, then reads it back from a
+// new request with get using readKeys.
+func cookieRoundTrip(t *testing.T, keys, readKeys *Keyring, set func(*Response), get func(*Request) (string, error)) (string, error) {
+	t.Helper()
+	httpReq := httptest.NewRequest("GET", "/", nil)
+	httpReq = httpReq.WithContext(ContextWithConfig(httpReq.Context(), &Config{Cookies: keys}))
+	rec := httptest.NewRecorder()
+	_, res := New(rec, httpReq)
+	set(res)
+
+	next := httptest.NewRequest("GET", "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		next.AddCookie(c)
+	}
+	next = next.WithContext(ContextWithConfig(next.Context(), &Config{Cookies: readKeys}))
+	return get(NewRequest(next))
+}
+
+func TestSetCookieSecureDefaults(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewResponse(rec).SetCookie("theme", "dark")
+
+	c := rec.Result().Cookies()[0]
+	if !c.HttpOnly || !c.Secure || c.SameSite != http.SameSiteLaxMode || c.Path != "/" {
+		t.Fatalf("Expected secure defaults, got %+v", c)
+	}
+
+	rec = httptest.NewRecorder()
+	NewResponse(rec).ClearCookie("theme")
+	if c := rec.Result().Cookies()[0]; c.MaxAge >= 0 {
+		t.Fatalf("Expected cleared cookie to expire, got MaxAge %d", c.MaxAge)
+	}
+}
+
+func TestSignedCookieRotation(t *testing.T) {
+	oldKeys, _ := NewKeyring(oldCookieKey)
+	rotated, _ := NewKeyring(newCookieKey, oldCookieKey)
+
+	set := func(res *Response) {
+		if err := res.SetSignedCookie("session", "user-42"); err != nil {
+			t.Fatalf("SetSignedCookie returned error: %v", err)
+		}
+	}
+	get := func(r *Request) (string, error) { return r.SignedCookie("session") }
+
+	if value, err := cookieRoundTrip(t, oldKeys, rotated, set, get); err != nil || value != "user-42" {
+		t.Fatalf("Expected old cookie to verify after rotation, got '%s' (%v)", value, err)
+	}
+
+	newOnly, _ := NewKeyring(newCookieKey)
+	if _, err := cookieRoundTrip(t, oldKeys, newOnly, set, get); !errors.Is(err, ErrInvalidCookie) {
+		t.Fatalf("Expected ErrInvalidCookie once the old key is dropped, got %v", err)
+	}
+}
+
+func TestSignedCookieTampering(t *testing.T) {
+	keys, _ := NewKeyring(newCookieKey)
+	signed, err := keys.Sign("session", "user-42")
+	if err != nil {
+		t.Fatalf("Sign returned error: %v", err)
+	}
+
+	if _, err := keys.Verify("session", "dXNlci0x"+signed[len("dXNlci00"):]); !errors.Is(err, ErrInvalidCookie) {
+		t.Fatalf("Expected ErrInvalidCookie for a changed value, got %v", err)
+	}
+	if _, err := keys.Verify("other", signed); !errors.Is(err, ErrInvalidCookie) {
+		t.Fatalf("Expected ErrInvalidCookie for a value moved to another cookie, got %v", err)
+	}
+}
+
+func TestEncryptedCookie(t *testing.T) {
+	oldKeys, _ := NewKeyring(oldCookieKey)
+	rotated, _ := NewKeyring(newCookieKey, oldCookieKey)
+
+	var raw string
+	set := func(res *Response) {
+		if err := res.SetEncryptedCookie("prefs", "secret-value"); err != nil {
+			t.Fatalf("SetEncryptedCookie returned error: %v", err)
+		}
+		raw = res.Header().Get("Set-Cookie")
+	}
+	get := func(r *Request) (string, error) { return r.EncryptedCookie("prefs") }
+
+	value, err := cookieRoundTrip(t, oldKeys, rotated, set, get)
+	if err != nil || value != "secret-value" {
+		t.Fatalf("Expected decrypted value, got '%s' (%v)", value, err)
+	}
+	if bytes.Contains([]byte(raw), []byte("secret-value")) {
+		t.Fatalf("Expected the cookie value to be encrypted, got %s", raw)
+	}
+}
+
+func TestKeyedCookiesRequireKeys(t *testing.T) {
+	r, res, _ := NewTestRequest("GET", "/", nil)
+	if err := res.SetSignedCookie("a", "b"); !errors.Is(err, ErrNoCookieKeys) {
+		t.Fatalf("Expected ErrNoCookieKeys, got %v", err)
+	}
+	if _, err := r.SignedCookie("a"); !errors.Is(err, ErrNoCookieKeys) {
+		t.Fatalf("Expected ErrNoCookieKeys, got %v", err)
+	}
+	if _, err := NewKeyring([]byte("short")); err == nil {
+		t.Fatal("Expected an error for a short key")
+	}
+	if _, err := NewKeyring(); !errors.Is(err, ErrNoCookieKeys) {
+		t.Fatalf("Expected ErrNoCookieKeys for no keys, got %v", err)
+	}
+
+	empty := &Keyring{}
+	if _, err := empty.Sign("a", "b"); !errors.Is(err, ErrNoCookieKeys) {
+		t.Fatalf("Expected ErrNoCookieKeys from an empty keyring, got %v", err)
+	}
+	if _, err := empty.Encrypt("a", "b"); !errors.Is(err, ErrNoCookieKeys) {
+		t.Fatalf("Expected ErrNoCookieKeys from an empty keyring, got %v", err)
+	}
+}
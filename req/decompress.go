@@ -0,0 +1,165 @@
+package req
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxDecompressedSize is the default limit, in bytes, on request
+// bodies after Content-Encoding has been removed.
+const DefaultMaxDecompressedSize int64 = 10 << 20
+
+// SupportedEncodings lists the request Content-Encodings DecompressBody can
+// remove, in the form used by the Accept-Encoding response header.
+const SupportedEncodings = "gzip, deflate"
+
+// UnsupportedEncodingError is returned for a request Content-Encoding that
+// cannot be decoded. It is answered with 415 Unsupported Media Type.
+type UnsupportedEncodingError struct {
+	Encoding string
+}
+
+func (e *UnsupportedEncodingError) Error() string {
+	return "unsupported Content-Encoding " + e.Encoding
+}
+
+// StatusCode returns 415 Unsupported Media Type.
+func (e *UnsupportedEncodingError) StatusCode() int {
+	return http.StatusUnsupportedMediaType
+}
+
+// ContentEncodingError is returned when a compressed body is corrupt. It is
+// answered with 400 Bad Request.
+type ContentEncodingError struct {
+	Encoding string
+	Err      error
+}
+
+func (e *ContentEncodingError) Error() string {
+	return "invalid " + e.Encoding + " body: " + e.Err.Error()
+}
+
+func (e *ContentEncodingError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode returns 400 Bad Request.
+func (e *ContentEncodingError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// DecompressBody replaces the body of a request sent with a gzip or deflate
+// Content-Encoding by a reader that decodes it, and removes the encoding
+// headers. Decoding is lazy; reading more than limit decoded bytes returns an
+// *http.MaxBytesError. A limit of zero or less disables it. Unknown encodings
+// return an *UnsupportedEncodingError and leave the request untouched.
+func DecompressBody(r *http.Request, limit int64) error {
+	header := r.Header.Get("Content-Encoding")
+	if header == "" || r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	var encodings []string
+	for _, encoding := range strings.Split(header, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		switch encoding {
+		case "identity", "":
+		case "gzip", "x-gzip", "deflate":
+			encodings = append(encodings, encoding)
+		default:
+			return &UnsupportedEncodingError{Encoding: encoding}
+		}
+	}
+	if len(encodings) == 0 {
+		r.Header.Del("Content-Encoding")
+		return nil
+	}
+
+	r.Body = &decompressedBody{src: r.Body, encodings: encodings, limit: limit}
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	return nil
+}
+
+// decompressedBody decodes its source on first read.
+type decompressedBody struct {
+	src       io.ReadCloser
+	encodings []string
+	limit     int64
+	reader    io.Reader
+	read      int64
+	err       error
+}
+
+func (b *decompressedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.reader == nil {
+		b.reader = b.src
+		// Encodings are listed in the order they were applied.
+		for i := len(b.encodings) - 1; i >= 0; i-- {
+			reader, err := decoder(b.encodings[i], b.reader)
+			if err != nil {
+				b.err = b.wrap(b.encodings[i], err)
+				return 0, b.err
+			}
+			b.reader = reader
+		}
+	}
+	if b.limit > 0 && int64(len(p)) > b.limit-b.read+1 {
+		p = p[:b.limit-b.read+1]
+	}
+
+	n, err := b.reader.Read(p)
+	b.read += int64(n)
+	if b.limit > 0 && b.read > b.limit {
+		b.err = &http.MaxBytesError{Limit: b.limit}
+		return n - int(b.read-b.limit), b.err
+	}
+	if err != nil && err != io.EOF {
+		b.err = b.wrap(strings.Join(b.encodings, ", "), err)
+		return n, b.err
+	}
+	return n, err
+}
+
+func (b *decompressedBody) Close() error {
+	return b.src.Close()
+}
+
+// wrap keeps size limit errors intact and reports anything else as corrupt
+// compressed data.
+func (b *decompressedBody) wrap(encoding string, err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &ContentEncodingError{Encoding: encoding, Err: err}
+}
+
+func decoder(encoding string, r io.Reader) (io.Reader, error) {
+	if encoding != "deflate" {
+		return gzip.NewReader(r)
+	}
+	// "deflate" should be zlib-wrapped, but some clients send raw deflate.
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
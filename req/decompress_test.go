@@ -0,0 +1,96 @@
+package req
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func compressedRequest(t *testing.T, encoding string, body []byte, limit int64) (*Request, error) {
+	t.Helper()
+	httpReq := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Encoding", encoding)
+	err := DecompressBody(httpReq, limit)
+	return NewRequest(httpReq), err
+}
+
+func TestDecompressBody(t *testing.T) {
+	payload := []byte(`{"name":"Gopher"}`)
+
+	tests := []struct {
+		name, header, format string
+	}{
+		{"gzip", "gzip", "gzip"},
+		{"zlib deflate", "deflate", "deflate"},
+		{"raw deflate", "deflate", "raw-deflate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := compressedRequest(t, tt.header, compress(t, tt.format, payload), 0)
+			if err != nil {
+				t.Fatalf("DecompressBody returned error: %v", err)
+			}
+			if got := r.BodyAsString(); got != string(payload) {
+				t.Fatalf("Expected decompressed body, got '%s'", got)
+			}
+			if r.Header("Content-Encoding") != "" {
+				t.Fatal("Expected Content-Encoding to be removed")
+			}
+		})
+	}
+}
+
+func TestDecompressBodyLimit(t *testing.T) {
+	bomb := compress(t, "gzip", bytes.Repeat([]byte("0"), 1<<20))
+	r, err := compressedRequest(t, "gzip", bomb, 1024)
+	if err != nil {
+		t.Fatalf("DecompressBody returned error: %v", err)
+	}
+
+	if _, err := r.BodyBytes(); StatusCode(err) != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected a 413 error, got %v", err)
+	}
+}
+
+func TestDecompressBodyErrors(t *testing.T) {
+	if _, err := compressedRequest(t, "br", []byte("x"), 0); StatusCode(err) != http.StatusUnsupportedMediaType {
+		t.Fatalf("Expected a 415 error for an unknown encoding, got %v", err)
+	}
+
+	r, _ := compressedRequest(t, "gzip", []byte("not gzip"), 0)
+	_, err := r.BodyBytes()
+	var encodingErr *ContentEncodingError
+	if !errors.As(err, &encodingErr) || StatusCode(err) != http.StatusBadRequest {
+		t.Fatalf("Expected a 400 ContentEncodingError, got %v", err)
+	}
+
+	truncated := compress(t, "gzip", []byte(strings.Repeat("data", 100)))
+	r, _ = compressedRequest(t, "gzip", truncated[:len(truncated)-10], 0)
+	if _, err := r.BodyBytes(); StatusCode(err) != http.StatusBadRequest {
+		t.Fatalf("Expected a 400 error for a truncated body, got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package req
+
+import (
+	"errors"
+	"net/http"
+)
+
+// StatusCode returns the HTTP status code that describes err. Errors can set
+// their own code by implementing StatusCode() int; anything else is a 500.
+func StatusCode(err error) int {
+	var coded interface{ StatusCode() int }
+	if errors.As(err, &coded) {
+		return coded.StatusCode()
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
+
+// HTTPError is an error with the response it should produce: a status code,
+// an optional machine-readable code, a message for the client and optional
+// details. Return it from a HandlerE to control the error response.
+type HTTPError struct {
+	Status  int
+	Code    string
+	Message string
+	Details interface{}
+}
+
+// NewHTTPError returns an HTTPError with the given status and message. An
+// empty message defaults to the status text.
+func NewHTTPError(status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Message == "" {
+		return http.StatusText(e.StatusCode())
+	}
+	return e.Message
+}
+
+// StatusCode returns the error's status, or 500 if none was set.
+func (e *HTTPError) StatusCode() int {
+	if e.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Status
+}
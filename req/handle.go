@@ -0,0 +1,56 @@
+package req
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/BrunoCiccarino/GopherLight/logger"
+)
+
+// HandlerE is a handler that returns its errors instead of writing them.
+// Wrap it with HandleE to register it as a Handler.
+type HandlerE func(r *Request, w *Response) error
+
+// ErrorHandler turns an error returned by a HandlerE, or recorded while
+// reading the request, into a response.
+type ErrorHandler func(err error, r *Request, w *Response)
+
+// HandleE adapts h to a Handler. A returned error is passed to the App's
+// error handler, DefaultErrorHandler unless SetErrorHandler replaced it.
+func HandleE(h HandlerE) Handler {
+	return func(r *Request, w *Response) {
+		if err := h(r, w); err != nil {
+			HandleError(err, r, w)
+		}
+	}
+}
+
+// HandleError passes err to the error handler configured for the request.
+func HandleError(err error, r *Request, w *Response) {
+	handler := ConfigFromContext(r.Context()).Errors
+	if handler == nil {
+		handler = DefaultErrorHandler
+	}
+	handler(err, r, w)
+}
+
+// DefaultErrorHandler logs server errors with the request method and path
+// and answers with problem details from ProblemFor. For server errors, the
+// message of errors that are not an *HTTPError or *Problem is not sent, so
+// internal details stay in the log. Nothing is sent if the response has
+// already been written.
+func DefaultErrorHandler(err error, r *Request, w *Response) {
+	status := StatusCode(err)
+	if status >= http.StatusInternalServerError {
+		logger.LogError("Error handling " + r.Req.Method + " " + r.Req.URL.Path + ": " + err.Error())
+	}
+	if w.Written() {
+		return
+	}
+	var httpErr *HTTPError
+	var problem *Problem
+	if status >= http.StatusInternalServerError && !errors.As(err, &httpErr) && !errors.As(err, &problem) {
+		err = NewProblem(status, "")
+	}
+	w.Error(err)
+}
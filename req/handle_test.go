@@ -0,0 +1,85 @@
+package req
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHandleEHTTPError(t *testing.T) {
+	handler := HandleE(func(r *Request, w *Response) error {
+		return &HTTPError{
+			Status:  http.StatusConflict,
+			Code:    "user_exists",
+			Message: "a user with this email already exists",
+			Details: map[string]string{"email": "gopher@example.com"},
+		}
+	})
+
+	r, res, rec := NewTestRequest("POST", "/users", nil)
+	handler(r, res)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d", http.StatusConflict, rec.Code)
+	}
+	var body map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if body["detail"] != "a user with this email already exists" || body["code"] != "user_exists" {
+		t.Fatalf("Unexpected error body: %v", body)
+	}
+	if details, _ := body["details"].(map[string]interface{}); details["email"] != "gopher@example.com" {
+		t.Fatalf("Expected details in error body, got %v", body)
+	}
+}
+
+func TestHandleEHidesInternalErrors(t *testing.T) {
+	handler := HandleE(func(r *Request, w *Response) error {
+		return errors.New("connection refused: db.internal:5432")
+	})
+
+	r, res, rec := NewTestRequest("GET", "/", nil)
+	handler(r, res)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d", rec.Code)
+	}
+	if ct := rec.Result().Header.Get("Content-Type"); ct != ProblemContentType {
+		t.Fatalf("Expected %s, got %s", ProblemContentType, ct)
+	}
+	if strings.Contains(rec.Body.String(), "db.internal") {
+		t.Fatalf("Expected internal error details to be hidden, got %s", rec.Body.String())
+	}
+}
+
+func TestHandleEAfterWrite(t *testing.T) {
+	handler := HandleE(func(r *Request, w *Response) error {
+		w.Send("partial")
+		return NewHTTPError(http.StatusBadRequest, "too late")
+	})
+
+	r, res, rec := NewTestRequest("GET", "/", nil)
+	handler(r, res)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+		t.Fatalf("Expected the written response to be kept, got %d '%s'", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleEUsesConfiguredHandler(t *testing.T) {
+	var got error
+	cfg := &Config{Errors: func(err error, r *Request, w *Response) {
+		got = err
+		w.Status(http.StatusTeapot).Send("custom")
+	}}
+
+	r, res, rec := NewTestRequest("GET", "/", nil)
+	r.Req = r.Req.WithContext(ContextWithConfig(r.Req.Context(), cfg))
+	want := errors.New("boom")
+	HandleE(func(r *Request, w *Response) error { return want })(r, res)
+
+	if got != want || rec.Code != http.StatusTeapot {
+		t.Fatalf("Expected the configured error handler to run, got %v and %d", got, rec.Code)
+	}
+}
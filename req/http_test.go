@@ -2,8 +2,10 @@ package req
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -83,3 +85,52 @@ func TestResponseJSON(t *testing.T) {
 		t.Fatalf("Expected message '%s', got '%s'", expectedBody["message"], responseBody["message"])
 	}
 }
+
+func TestRequestBodyIsLazy(t *testing.T) {
+	httpReq := httptest.NewRequest("POST", "/", strings.NewReader("payload"))
+	r := NewRequest(httpReq)
+
+	if _, ok := httpReq.Body.(*bufferedBody); ok {
+		t.Fatal("Expected NewRequest not to read the body")
+	}
+	if body := r.BodyAsString(); body != "payload" {
+		t.Fatalf("Expected body 'payload', got '%s'", body)
+	}
+	if body := r.BodyAsString(); body != "payload" {
+		t.Fatalf("Expected body to be readable twice, got '%s'", body)
+	}
+	if body := r.Body(); body != "payload" {
+		t.Fatalf("Expected the deprecated Body to return 'payload', got '%s'", body)
+	}
+}
+
+func TestBufferBodySharedWithHandler(t *testing.T) {
+	httpReq := httptest.NewRequest("POST", "/", strings.NewReader("shared"))
+
+	data, err := BufferBody(httpReq)
+	if err != nil || string(data) != "shared" {
+		t.Fatalf("Expected middleware to read 'shared', got '%s' (%v)", data, err)
+	}
+
+	r := NewRequest(httpReq)
+	if body := r.BodyAsString(); body != "shared" {
+		t.Fatalf("Expected handler to read 'shared', got '%s'", body)
+	}
+	streamed, _ := io.ReadAll(r.BodyReader())
+	if string(streamed) != "shared" {
+		t.Fatalf("Expected streamed body 'shared', got '%s'", streamed)
+	}
+}
+
+func TestBodyTooLargeRecordsError(t *testing.T) {
+	httpReq := httptest.NewRequest("POST", "/", strings.NewReader("0123456789"))
+	httpReq.Body = http.MaxBytesReader(nil, httpReq.Body, 4)
+	r := NewRequest(httpReq)
+
+	if _, err := r.BodyBytes(); err == nil {
+		t.Fatal("Expected an error for a body over the limit")
+	}
+	if status := StatusCode(r.Err()); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, status)
+	}
+}
@@ -0,0 +1,70 @@
+package req
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/BrunoCiccarino/GopherLight/logger"
+)
+
+// ContextCarrier is anything that carries a request context, such as an
+// *http.Request in middleware or a *Request in a handler.
+type ContextCarrier interface {
+	Context() context.Context
+}
+
+// locals is the per-request store behind Set and Get. It is shared by every
+// copy of the request made with WithContext, so values set in middleware are
+// visible to later middleware and to the handler.
+type locals struct {
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+type localsKey struct{}
+
+// WithLocals returns r with a locals store in its context. The App does this
+// for every request; call it only in middleware or tests that run without an
+// App. If r already has a store it is returned unchanged.
+func WithLocals(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(localsKey{}).(*locals); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), localsKey{}, &locals{values: map[string]interface{}{}}))
+}
+
+// Set stores value under key for the rest of the request.
+func Set(r ContextCarrier, key string, value interface{}) {
+	store, ok := r.Context().Value(localsKey{}).(*locals)
+	if !ok {
+		logger.LogWarning("req.Set called on a request without locals; wrap it with req.WithLocals")
+		return
+	}
+	store.mu.Lock()
+	store.values[key] = value
+	store.mu.Unlock()
+}
+
+// Get returns the value stored under key. The second result is false when
+// there is no value or it is not a T.
+func Get[T any](r ContextCarrier, key string) (T, bool) {
+	var zero T
+	store, ok := r.Context().Value(localsKey{}).(*locals)
+	if !ok {
+		return zero, false
+	}
+	store.mu.RLock()
+	value, ok := store.values[key]
+	store.mu.RUnlock()
+	if !ok {
+		return zero, false
+	}
+	typed, ok := value.(T)
+	return typed, ok
+}
+
+// Context returns the request's context.
+func (r *Request) Context() context.Context {
+	return r.Req.Context()
+}
@@ -0,0 +1,42 @@
+package req
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+type user struct{ Name string }
+
+func TestLocalsSharedAcrossRequestCopies(t *testing.T) {
+	httpReq := WithLocals(httptest.NewRequest("GET", "/", nil))
+	Set(httpReq, "user", &user{Name: "Gopher"})
+	Set(httpReq, "count", 3)
+
+	// A middleware further down may derive a new request with WithContext.
+	r := NewRequest(httpReq.WithContext(httpReq.Context()))
+
+	if u, ok := Get[*user](r, "user"); !ok || u.Name != "Gopher" {
+		t.Fatalf("Expected user to be visible to the handler, got %v (%v)", u, ok)
+	}
+	if n, ok := Get[int](r, "count"); !ok || n != 3 {
+		t.Fatalf("Expected count 3, got %d (%v)", n, ok)
+	}
+	if _, ok := Get[string](r, "count"); ok {
+		t.Fatal("Expected a type mismatch to report false")
+	}
+	if _, ok := Get[int](r, "missing"); ok {
+		t.Fatal("Expected a missing key to report false")
+	}
+}
+
+func TestWithLocalsKeepsExistingStore(t *testing.T) {
+	httpReq := WithLocals(httptest.NewRequest("GET", "/", nil))
+	Set(httpReq, "a", 1)
+
+	if again := WithLocals(httpReq); again != httpReq {
+		t.Fatal("Expected WithLocals to reuse the existing store")
+	}
+	if _, ok := Get[int](httptest.NewRequest("GET", "/", nil), "a"); ok {
+		t.Fatal("Expected no value on a request without locals")
+	}
+}
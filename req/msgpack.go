@@ -0,0 +1,80 @@
+package req
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+)
+
+// msgpackCodec implements MessagePack. Struct fields are named by their
+// `msgpack` tag, falling back to the `json` tag. time.Time uses the timestamp
+// extension type.
+type msgpackCodec struct{}
+
+var errMsgpackDepth = errors.New("msgpack: value nested too deeply")
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	enc.UseCompactFloats(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	r := bytes.NewReader(data)
+	if err := checkMsgpack(msgpack.NewDecoder(r), 0); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return errors.New("msgpack: unexpected data after top-level value")
+	}
+
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	// Decode integers into interface{} values as int64 or uint64 and floats
+	// as float64, like the other codecs.
+	dec.UseLooseInterfaceDecoding(true)
+	return dec.Decode(v)
+}
+
+// checkMsgpack walks one value before it is decoded. The decoder neither
+// limits nesting nor checks the declared length of an array against the
+// input, so a few bytes could otherwise exhaust the stack or memory.
+func checkMsgpack(dec *msgpack.Decoder, depth int) error {
+	if depth > maxCodecDepth {
+		return errMsgpackDepth
+	}
+	c, err := dec.PeekCode()
+	if err != nil {
+		return err
+	}
+
+	var n int
+	switch {
+	case msgpcode.IsFixedArray(c) || c == msgpcode.Array16 || c == msgpcode.Array32:
+		n, err = dec.DecodeArrayLen()
+	case msgpcode.IsFixedMap(c) || c == msgpcode.Map16 || c == msgpcode.Map32:
+		n, err = dec.DecodeMapLen()
+		n *= 2
+	default:
+		return dec.Skip()
+	}
+	if err != nil {
+		return err
+	}
+	// Every element takes at least one byte, so a length the input cannot
+	// hold fails as soon as the data runs out.
+	for i := 0; i < n; i++ {
+		if err := checkMsgpack(dec, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
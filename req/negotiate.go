@@ -0,0 +1,184 @@
+package req
+
+import (
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mediaTypeAliases are the short names accepted by Accepts and Format.
+var mediaTypeAliases = map[string]string{
+	"json":    "application/json",
+	"xml":     "application/xml",
+	"html":    "text/html",
+	"text":    "text/plain",
+	"yaml":    "application/yaml",
+	"msgpack": "application/msgpack",
+	"cbor":    "application/cbor",
+}
+
+// formatPreference orders Format's offers when the client accepts several
+// of them equally, or sends no Accept header at all.
+var formatPreference = []string{"json", "xml", "html", "text", "yaml", "msgpack", "cbor"}
+
+// NotAcceptableError is returned when none of the offered representations is
+// acceptable to the client. It is answered with 406 Not Acceptable.
+type NotAcceptableError struct {
+	Offers []string
+}
+
+func (e *NotAcceptableError) Error() string {
+	return "not acceptable: supported types are " + strings.Join(e.Offers, ", ")
+}
+
+// StatusCode returns 406 Not Acceptable.
+func (e *NotAcceptableError) StatusCode() int {
+	return http.StatusNotAcceptable
+}
+
+// acceptRange is one entry of an Accept header.
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// Accepts returns the offer the client prefers according to the Accept
+// header and its q-values, or "" if none is acceptable. Offers are media
+// types or the short names json, xml, html, text, yaml, msgpack and cbor;
+// earlier offers win ties.
+// Without an Accept header the first offer is returned.
+func (r *Request) Accepts(offers ...string) string {
+	return negotiate(r.Req.Header.Values("Accept"), offers)
+}
+
+func negotiate(accept []string, offers []string) string {
+	ranges := parseAccept(accept)
+	if ranges == nil {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(ranges, resolveMediaType(offer)); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// parseAccept returns the ranges listed in the Accept header values, or nil
+// if there are none.
+func parseAccept(values []string) []acceptRange {
+	var ranges []acceptRange
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			q := 1.0
+			if raw, ok := params["q"]; ok {
+				if parsed, err := strconv.ParseFloat(raw, 64); err == nil && parsed >= 0 && parsed <= 1 {
+					q = parsed
+				}
+			}
+			ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+		}
+	}
+	return ranges
+}
+
+// MatchMediaType reports whether mediaType, a lower-case type without
+// parameters as returned by mime.ParseMediaType, matches pattern, which may
+// be a full type, "type/*" or "*/*". Case and surrounding spaces in pattern
+// are ignored.
+func MatchMediaType(pattern, mediaType string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(mediaType, prefix+"/")
+	}
+	return false
+}
+
+// acceptQuality returns the q-value of the most specific range matching
+// mediaType, or 0 when no range matches.
+func acceptQuality(ranges []acceptRange, mediaType string) float64 {
+	q, specificity := 0.0, 0
+	for _, rng := range ranges {
+		s := 0
+		switch {
+		case rng.mediaType == mediaType:
+			s = 3
+		case rng.mediaType == "*/*":
+			s = 1
+		case MatchMediaType(rng.mediaType, mediaType):
+			s = 2
+		}
+		if s > specificity {
+			q, specificity = rng.q, s
+		}
+	}
+	return q
+}
+
+func resolveMediaType(offer string) string {
+	if mediaType, ok := mediaTypeAliases[offer]; ok {
+		return mediaType
+	}
+	return strings.ToLower(offer)
+}
+
+// Format calls the handler for the representation the client prefers. Keys
+// are media types or the short names accepted by Accepts. A "default"
+// key is called when nothing else is acceptable; without it the client gets
+// 406 Not Acceptable.
+func (res *Response) Format(handlers map[string]func()) {
+	res.Header().Add("Vary", "Accept")
+
+	offers := make([]string, 0, len(handlers))
+	for offer := range handlers {
+		if offer != "default" {
+			offers = append(offers, offer)
+		}
+	}
+	sort.SliceStable(offers, func(i, j int) bool {
+		return formatRank(offers[i]) < formatRank(offers[j]) ||
+			formatRank(offers[i]) == formatRank(offers[j]) && offers[i] < offers[j]
+	})
+
+	var accept []string
+	if res.req != nil {
+		accept = res.req.Header.Values("Accept")
+	}
+	if offer := negotiate(accept, offers); offer != "" {
+		handlers[offer]()
+		return
+	}
+	if fallback, ok := handlers["default"]; ok {
+		fallback()
+		return
+	}
+	res.Error(&NotAcceptableError{Offers: offers})
+}
+
+func formatRank(offer string) int {
+	for i, name := range formatPreference {
+		if offer == name || offer == mediaTypeAliases[name] {
+			return i
+		}
+	}
+	return len(formatPreference)
+}
+
+// XML serializes data with the application/xml codec and sends it.
+func (res *Response) XML(data interface{}) {
+	res.Encode("application/xml", data)
+}
@@ -0,0 +1,82 @@
+package req
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		accept string
+		offers []string
+		want   string
+	}{
+		{"", []string{"json", "xml"}, "json"},
+		{"application/xml", []string{"json", "xml"}, "xml"},
+		{"application/json;q=0.5, application/xml", []string{"json", "xml"}, "xml"},
+		{"text/*;q=0.9, */*;q=0.1", []string{"application/json", "text/html"}, "text/html"},
+		{"text/html, */*;q=0", []string{"json"}, ""},
+		{"application/*, application/xml;q=0", []string{"xml", "json"}, "json"},
+	}
+
+	for _, tt := range tests {
+		r, _, _ := NewTestRequest("GET", "/", nil)
+		if tt.accept != "" {
+			r.Req.Header.Set("Accept", tt.accept)
+		}
+		if got := r.Accepts(tt.offers...); got != tt.want {
+			t.Errorf("Accept %q with offers %v: expected '%s', got '%s'", tt.accept, tt.offers, tt.want, got)
+		}
+	}
+}
+
+func TestMatchMediaType(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		mediaType string
+		want      bool
+	}{
+		{"application/json", "application/json", true},
+		{" Application/JSON ", "application/json", true},
+		{"image/*", "image/png", true},
+		{"image/*", "imagex/png", false},
+		{"*/*", "text/plain", true},
+		{"text/plain", "text/html", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchMediaType(tt.pattern, tt.mediaType); got != tt.want {
+			t.Errorf("MatchMediaType(%q, %q) = %v, want %v", tt.pattern, tt.mediaType, got, tt.want)
+		}
+	}
+}
+
+type greeting struct {
+	Message string `json:"message" xml:"message"`
+}
+
+func TestFormat(t *testing.T) {
+	respond := func(accept string) (int, string, string) {
+		r, res, rec := NewTestRequest("GET", "/", nil)
+		r.Req.Header.Set("Accept", accept)
+		data := greeting{Message: "hi"}
+		res.Format(map[string]func(){
+			"json": func() { res.JSON(data) },
+			"xml":  func() { res.XML(data) },
+			"text": func() { res.Send(data.Message) },
+		})
+		return rec.Code, rec.Header().Get("Content-Type"), rec.Body.String()
+	}
+
+	if _, contentType, _ := respond("*/*"); contentType != "application/json" {
+		t.Fatalf("Expected JSON for */*, got %s", contentType)
+	}
+	status, contentType, body := respond("application/xml")
+	if status != http.StatusOK || contentType != "application/xml" || !strings.Contains(body, "<greeting><message>hi</message></greeting>") {
+		t.Fatalf("Expected XML response, got %d %s %s", status, contentType, body)
+	}
+	if status, _, _ := respond("image/png"); status != http.StatusNotAcceptable {
+		t.Fatalf("Expected 406, got %d", status)
+	}
+}
@@ -0,0 +1,162 @@
+package req
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/BrunoCiccarino/GopherLight/logger"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. It is an error, so handlers
+// wrapped with HandleE can return it, and Response.Error sends it as
+// application/problem+json. Extensions are added as top-level members.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// NewProblem returns a Problem with the given status and detail. Its title is
+// the status text.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{Status: status, Title: http.StatusText(status), Detail: detail}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	if p.Title != "" {
+		return p.Title
+	}
+	return http.StatusText(p.StatusCode())
+}
+
+// StatusCode returns the problem's status, or 500 if none was set.
+func (p *Problem) StatusCode() int {
+	if p.Status == 0 {
+		return http.StatusInternalServerError
+	}
+	return p.Status
+}
+
+// ProblemFor returns the problem details that describe err, with the status
+// code from StatusCode(err). A *Problem is returned as is. An *HTTPError
+// keeps its message as the detail and adds its code and details as the
+// "code" and "details" members. Binding and validation errors list each
+// failing input in a "fields" member. Any other error uses its message as
+// the detail.
+func ProblemFor(err error) *Problem {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		return problem
+	}
+	p := NewProblem(StatusCode(err), err.Error())
+	var httpErr *HTTPError
+	var bindErr *BindError
+	var validationErrs ValidationErrors
+	switch {
+	case errors.As(err, &httpErr):
+		p.Detail = httpErr.Message
+		p.Extensions = map[string]interface{}{}
+		if httpErr.Code != "" {
+			p.Extensions["code"] = httpErr.Code
+		}
+		if httpErr.Details != nil {
+			p.Extensions["details"] = httpErr.Details
+		}
+	case errors.As(err, &bindErr):
+		p.Detail = "invalid input"
+		p.Extensions = map[string]interface{}{"fields": bindErr.Fields}
+	case errors.As(err, &validationErrs):
+		p.Detail = "validation failed"
+		p.Extensions = map[string]interface{}{"fields": validationErrs}
+	}
+	return p
+}
+
+// MarshalJSON writes the standard members followed by the extensions. The
+// type defaults to "about:blank", whose title is the status text.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for name, value := range p.Extensions {
+		members[name] = value
+	}
+	members["type"] = p.Type
+	if p.Type == "" {
+		members["type"] = "about:blank"
+	}
+	if p.Title != "" {
+		members["title"] = p.Title
+	} else if p.Type == "" || p.Type == "about:blank" {
+		members["title"] = http.StatusText(p.StatusCode())
+	}
+	members["status"] = p.StatusCode()
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	return json.Marshal(members)
+}
+
+// UnmarshalJSON reads the standard members and keeps any others as
+// extensions.
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	*p = Problem{}
+	fields := map[string]interface{}{
+		"type":     &p.Type,
+		"title":    &p.Title,
+		"status":   &p.Status,
+		"detail":   &p.Detail,
+		"instance": &p.Instance,
+	}
+	for name, raw := range members {
+		if field, ok := fields[name]; ok {
+			if err := json.Unmarshal(raw, field); err != nil {
+				return err
+			}
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		if p.Extensions == nil {
+			p.Extensions = map[string]interface{}{}
+		}
+		p.Extensions[name] = value
+	}
+	return nil
+}
+
+// Problem sends p as application/problem+json with its status code. The
+// instance defaults to the request path.
+func (res *Response) Problem(p *Problem) {
+	if p.Instance == "" && res.req != nil {
+		copied := *p
+		copied.Instance = res.req.URL.Path
+		p = &copied
+	}
+	body, err := json.Marshal(p)
+	if err != nil {
+		logger.LogError("Error encoding problem response: " + err.Error())
+		res.JSONError("Error encoding response")
+		return
+	}
+	res.Header().Set("Content-Type", ProblemContentType)
+	res.writeStatusIfNotWritten(p.StatusCode())
+	res.Write(body)
+}
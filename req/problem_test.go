@@ -0,0 +1,91 @@
+package req
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseProblem(t *testing.T) {
+	_, res, rec := NewTestRequest("GET", "/accounts/12345/msgs/abc", nil)
+	res.Problem(&Problem{
+		Type:       "https://example.com/probs/out-of-credit",
+		Title:      "You do not have enough credit.",
+		Status:     http.StatusForbidden,
+		Detail:     "Your current balance is 30, but that costs 50.",
+		Extensions: map[string]interface{}{"balance": 30},
+	})
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != ProblemContentType {
+		t.Fatalf("Expected Content-Type %s, got %s", ProblemContentType, ct)
+	}
+
+	var got Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid problem body: %v", err)
+	}
+	if got.Type != "https://example.com/probs/out-of-credit" || got.Status != http.StatusForbidden ||
+		got.Instance != "/accounts/12345/msgs/abc" || got.Extensions["balance"] != 30.0 {
+		t.Fatalf("Unexpected problem: %+v", got)
+	}
+}
+
+func TestProblemDefaults(t *testing.T) {
+	data, _ := json.Marshal(&Problem{Status: http.StatusNotFound})
+	want := `{"status":404,"title":"Not Found","type":"about:blank"}`
+	if string(data) != want {
+		t.Fatalf("Expected %s, got %s", want, data)
+	}
+}
+
+func TestErrorSendsProblem(t *testing.T) {
+	_, res, rec := NewTestRequest("GET", "/", nil)
+	res.Error(NewProblem(http.StatusConflict, "already exists"))
+
+	if rec.Code != http.StatusConflict || rec.Header().Get("Content-Type") != ProblemContentType {
+		t.Fatalf("Expected a 409 problem response, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestProblemFor(t *testing.T) {
+	bindErr := &BindError{Fields: []FieldError{{Field: "age", Source: "json", Message: "must be an integer"}}}
+	validationErrs := ValidationErrors{{Field: "name", Rule: "required", Message: "is required"}}
+
+	tests := []struct {
+		err    error
+		status int
+		detail string
+		member string
+	}{
+		{bindErr, http.StatusBadRequest, "invalid input", "fields"},
+		{validationErrs, http.StatusUnprocessableEntity, "validation failed", "fields"},
+		{&HTTPError{Status: http.StatusConflict, Code: "taken", Message: "name taken"}, http.StatusConflict, "name taken", "code"},
+		{&NotAcceptableError{Offers: []string{"application/json"}}, http.StatusNotAcceptable, "not acceptable: supported types are application/json", ""},
+	}
+
+	for _, tt := range tests {
+		p := ProblemFor(tt.err)
+		if p.StatusCode() != tt.status || p.Detail != tt.detail {
+			t.Errorf("%T: got %d '%s', want %d '%s'", tt.err, p.StatusCode(), p.Detail, tt.status, tt.detail)
+		}
+		if _, ok := p.Extensions[tt.member]; tt.member != "" && !ok {
+			t.Errorf("%T: expected a %s member, got %v", tt.err, tt.member, p.Extensions)
+		}
+	}
+}
+
+func TestJSONErrorDefaultsTo500(t *testing.T) {
+	w := httptest.NewRecorder()
+	NewResponse(w).JSONError("something broke")
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d", w.Code)
+	}
+	if w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected JSON content type, got %s", w.Header().Get("Content-Type"))
+	}
+}
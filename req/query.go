@@ -0,0 +1,87 @@
+package req
+
+import (
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// queryTimeLayouts are the formats accepted by QueryTime, tried in order.
+var queryTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+// Query returns the parsed query string. It is parsed once per request.
+func (r *Request) Query() url.Values {
+	if r.query == nil {
+		r.query = r.Req.URL.Query()
+		if r.query == nil {
+			r.query = url.Values{}
+		}
+	}
+	return r.query
+}
+
+// QueryAll returns every value of the query parameter key.
+func (r *Request) QueryAll(key string) []string {
+	return r.Query()[key]
+}
+
+// QueryInt returns the query parameter key as an int, or def when it is
+// missing or empty. A value that is not an integer returns a *BindError,
+// which is also recorded on the request so an unwritten response gets a 400.
+func (r *Request) QueryInt(key string, def int) (int, error) {
+	n := def
+	err := r.queryScalar(key, &n)
+	return n, err
+}
+
+// QueryBool returns the query parameter key as a bool, or def when it is
+// missing or empty. It accepts the values understood by strconv.ParseBool.
+func (r *Request) QueryBool(key string, def bool) (bool, error) {
+	b := def
+	err := r.queryScalar(key, &b)
+	return b, err
+}
+
+// QueryDuration returns the query parameter key as a time.Duration such as
+// "1m30s", or def when it is missing or empty.
+func (r *Request) QueryDuration(key string, def time.Duration) (time.Duration, error) {
+	d := def
+	err := r.queryScalar(key, &d)
+	return d, err
+}
+
+// QueryTime returns the query parameter key as a time.Time, or def when it is
+// missing or empty. It accepts RFC 3339 timestamps and plain dates
+// ("2006-01-02"); values without a zone are read as UTC.
+func (r *Request) QueryTime(key string, def time.Time) (time.Time, error) {
+	value := r.Query().Get(key)
+	if value == "" {
+		return def, nil
+	}
+	for _, layout := range queryTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return def, r.queryError(key, value, "must be "+describeType(timeType))
+}
+
+// queryScalar parses the query parameter key into dst, leaving dst untouched
+// when the parameter is missing or empty.
+func (r *Request) queryScalar(key string, dst interface{}) error {
+	value := r.Query().Get(key)
+	if value == "" {
+		return nil
+	}
+	if err := setScalar(reflect.ValueOf(dst).Elem(), value); err != nil {
+		return r.queryError(key, value, err.Error())
+	}
+	return nil
+}
+
+func (r *Request) queryError(key, value, message string) error {
+	err := &BindError{}
+	err.add(key, "query", value, message)
+	r.fail(err)
+	return err
+}
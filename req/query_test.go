@@ -0,0 +1,55 @@
+package req
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTypedQueryAccessors(t *testing.T) {
+	r := NewRequest(httptest.NewRequest("GET", "/?page=3&debug=true&wait=1m30s&since=2024-05-01&tag=a&tag=b", nil))
+
+	if page, err := r.QueryInt("page", 1); err != nil || page != 3 {
+		t.Fatalf("Expected page 3, got %d (%v)", page, err)
+	}
+	if limit, err := r.QueryInt("limit", 20); err != nil || limit != 20 {
+		t.Fatalf("Expected default limit 20, got %d (%v)", limit, err)
+	}
+	if debug, err := r.QueryBool("debug", false); err != nil || !debug {
+		t.Fatalf("Expected debug true, got %v (%v)", debug, err)
+	}
+	if wait, err := r.QueryDuration("wait", 0); err != nil || wait != 90*time.Second {
+		t.Fatalf("Expected wait 1m30s, got %v (%v)", wait, err)
+	}
+	since, err := r.QueryTime("since", time.Time{})
+	if err != nil || !since.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected since 2024-05-01, got %v (%v)", since, err)
+	}
+	if tags := r.QueryAll("tag"); len(tags) != 2 || tags[1] != "b" {
+		t.Fatalf("Expected tags [a b], got %v", tags)
+	}
+	if r.Err() != nil {
+		t.Fatalf("Expected no recorded error, got %v", r.Err())
+	}
+}
+
+func TestInvalidQueryRecordsBadRequest(t *testing.T) {
+	r := NewRequest(httptest.NewRequest("GET", "/?page=two&since=yesterday", nil))
+
+	page, err := r.QueryInt("page", 1)
+	if err == nil || page != 1 {
+		t.Fatalf("Expected an error and the default page, got %d (%v)", page, err)
+	}
+	if _, err := r.QueryTime("since", time.Time{}); err == nil {
+		t.Fatal("Expected an error for an invalid time")
+	}
+
+	if got := StatusCode(r.Err()); got != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d", got)
+	}
+	fields := r.Err().(*BindError).Fields
+	if fields[0].Field != "page" || fields[0].Source != "query" || fields[0].Message != "must be an integer" {
+		t.Fatalf("Unexpected field error: %+v", fields[0])
+	}
+}
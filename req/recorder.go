@@ -0,0 +1,104 @@
+package req
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RecordingWriter is an http.ResponseWriter that records the status code,
+// the number of body bytes written and the time to the first byte. Middleware
+// wraps the writer with Record to inspect the response after calling the
+// next handler.
+type RecordingWriter struct {
+	http.ResponseWriter
+	start   time.Time
+	status  int
+	bytes   int64
+	ttfb    time.Duration
+	written bool
+}
+
+// Record returns a RecordingWriter for w. If w already is one, it is
+// returned as is, so every layer of a request shares the same record.
+func Record(w http.ResponseWriter) *RecordingWriter {
+	if rec, ok := w.(*RecordingWriter); ok {
+		return rec
+	}
+	return &RecordingWriter{ResponseWriter: w, start: time.Now()}
+}
+
+// WriteHeader records and sends the status code. Informational (1xx) codes
+// are passed through without ending the header phase; later final codes
+// are ignored, as net/http does.
+func (w *RecordingWriter) WriteHeader(statusCode int) {
+	if w.written {
+		return
+	}
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	w.status = statusCode
+	w.ttfb = time.Since(w.start)
+	w.written = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write sends data, sending a 200 status first if none was set.
+func (w *RecordingWriter) Write(data []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(data)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush sends any buffered data to the client.
+func (w *RecordingWriter) Flush() {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack takes over the connection, as for a WebSocket, and records
+// 101 Switching Protocols.
+func (w *RecordingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && !w.written {
+		w.status = http.StatusSwitchingProtocols
+		w.ttfb = time.Since(w.start)
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (w *RecordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status returns the status code sent, 200 if only the body was written,
+// or 0 if nothing has been sent yet.
+func (w *RecordingWriter) Status() int {
+	return w.status
+}
+
+// Written reports whether the status code has been sent.
+func (w *RecordingWriter) Written() bool {
+	return w.written
+}
+
+// BytesWritten returns the number of body bytes written.
+func (w *RecordingWriter) BytesWritten() int64 {
+	return w.bytes
+}
+
+// TimeToFirstByte returns the time from Record to the status code being
+// sent, or 0 if it has not been sent yet.
+func (w *RecordingWriter) TimeToFirstByte() time.Duration {
+	return w.ttfb
+}
@@ -0,0 +1,48 @@
+package req
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecordingWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	rec := Record(w)
+
+	if rec.Status() != 0 || rec.Written() {
+		t.Fatal("Expected nothing to be recorded before the response is written")
+	}
+
+	rec.WriteHeader(http.StatusNotFound)
+	rec.WriteHeader(http.StatusOK)
+	rec.Write([]byte("not found"))
+
+	if rec.Status() != http.StatusNotFound || w.Code != http.StatusNotFound {
+		t.Fatalf("Expected status 404, got %d (sent %d)", rec.Status(), w.Code)
+	}
+	if rec.BytesWritten() != 9 {
+		t.Fatalf("Expected 9 bytes written, got %d", rec.BytesWritten())
+	}
+	if rec.TimeToFirstByte() <= 0 {
+		t.Fatal("Expected time to first byte to be recorded")
+	}
+	if Record(rec) != rec {
+		t.Fatal("Expected Record to reuse an existing RecordingWriter")
+	}
+}
+
+func TestResponseSharesRecord(t *testing.T) {
+	w := httptest.NewRecorder()
+	rec := Record(w)
+	res := NewResponse(rec)
+
+	res.Status(http.StatusCreated).Send("done")
+
+	if rec.Status() != http.StatusCreated || rec.BytesWritten() != 4 {
+		t.Fatalf("Expected middleware record to see 201 and 4 bytes, got %d and %d", rec.Status(), rec.BytesWritten())
+	}
+	if res.StatusCode() != http.StatusCreated || res.BytesWritten() != 4 {
+		t.Fatalf("Unexpected response record: %d, %d", res.StatusCode(), res.BytesWritten())
+	}
+}
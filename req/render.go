@@ -0,0 +1,38 @@
+package req
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/BrunoCiccarino/GopherLight/logger"
+)
+
+// Renderer renders a named template with data. *view.Engine implements it.
+type Renderer interface {
+	Render(w io.Writer, name string, data interface{}) error
+}
+
+// Render renders the template name with data through the App's renderer
+// and sends it as HTML with a 200 status unless another status was set.
+// The page is rendered completely before anything is sent, so a template
+// error produces a clean 500 instead of half a page. A missing template is
+// also a 500, since it is a bug in the handler rather than the request.
+func (res *Response) Render(name string, data interface{}) {
+	renderer := res.config().Views
+	if renderer == nil {
+		logger.LogError("No renderer set for " + name + "; call App.SetViews")
+		res.Error(NewProblem(http.StatusInternalServerError, ""))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, name, data); err != nil {
+		logger.LogError("Error rendering " + name + ": " + err.Error())
+		res.Error(NewProblem(http.StatusInternalServerError, ""))
+		return
+	}
+	res.Header().Set("Content-Type", "text/html; charset=utf-8")
+	res.writeStatusIfNotWritten(http.StatusOK)
+	res.Write(buf.Bytes())
+}
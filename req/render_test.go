@@ -0,0 +1,55 @@
+package req
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+type stubRenderer struct{}
+
+func (stubRenderer) Render(w io.Writer, name string, data interface{}) error {
+	if name == "broken" {
+		fmt.Fprint(w, "<p>half a page")
+		return errors.New("template: broken: executing failed")
+	}
+	_, err := fmt.Fprintf(w, "<h1>%s: %v</h1>", name, data)
+	return err
+}
+
+func renderRequest(renderer Renderer) (*Request, *Response, func() (int, string, string)) {
+	r, res, rec := NewTestRequest("GET", "/", nil)
+	cfg := &Config{Views: renderer}
+	r.Req = r.Req.WithContext(ContextWithConfig(r.Req.Context(), cfg))
+	_, res = New(rec, r.Req)
+	return r, res, func() (int, string, string) {
+		return rec.Code, rec.Header().Get("Content-Type"), rec.Body.String()
+	}
+}
+
+func TestResponseRender(t *testing.T) {
+	_, res, result := renderRequest(stubRenderer{})
+	res.Render("users/show", "gopher")
+
+	status, contentType, body := result()
+	if status != http.StatusOK || contentType != "text/html; charset=utf-8" || body != "<h1>users/show: gopher</h1>" {
+		t.Fatalf("Unexpected render result: %d %s '%s'", status, contentType, body)
+	}
+}
+
+func TestResponseRenderError(t *testing.T) {
+	_, res, result := renderRequest(stubRenderer{})
+	res.Render("broken", nil)
+
+	if status, _, body := result(); status != http.StatusInternalServerError || body == "<p>half a page" {
+		t.Fatalf("Expected a clean 500, got %d '%s'", status, body)
+	}
+
+	_, res, result = renderRequest(nil)
+	res.Render("users/show", nil)
+	if status, _, _ := result(); status != http.StatusInternalServerError {
+		t.Fatalf("Expected 500 without a renderer, got %d", status)
+	}
+}
@@ -1,40 +1,100 @@
 package req
 
 import (
+	"bytes"
 	"io"
 	"net/http"
+	"net/url"
 
+	"github.com/BrunoCiccarino/GopherLight/clientip"
 	"github.com/BrunoCiccarino/GopherLight/logger"
 )
 
 type Request struct {
-	Req  *http.Request
-	Body string
+	Req        *http.Request
+	err        error
+	bodyLog    *BodyLogPolicy
+	bodyLogged bool
+	form       *multipartForm
+	query      url.Values
 }
 
+// NewRequest wraps req. The body is not read until the handler asks for it.
 func NewRequest(req *http.Request) *Request {
-	bodyBytes, err := io.ReadAll(req.Body)
-	if err != nil {
-		logger.LogError("Error reading request body: " + err.Error())
-		return &Request{Req: req, Body: ""}
-	}
-	bodyString := string(bodyBytes)
-	logger.LogInfo("Received request body: " + bodyString)
-
-	return &Request{
-		Req:  req,
-		Body: bodyString,
-	}
+	return &Request{Req: req}
 }
 
 func (r *Request) QueryParam(key string) string {
-	return r.Req.URL.Query().Get(key)
+	return r.Query().Get(key)
+}
+
+// Param returns the path parameter captured by a ":name" route segment.
+func (r *Request) Param(name string) string {
+	return r.Req.PathValue(name)
+}
+
+// ClientIP returns the address of the client. Forwarding headers are only
+// followed across the proxies trusted with App.SetTrustedProxies.
+func (r *Request) ClientIP() string {
+	return clientip.FromRequest(r.Req)
 }
 
 func (r *Request) Header(key string) string {
 	return r.Req.Header.Get(key)
 }
 
+// BodyBytes reads the whole body into memory on first use and returns it.
+// The buffered copy is shared with any middleware that called BufferBody.
+func (r *Request) BodyBytes() ([]byte, error) {
+	data, err := BufferBody(r.Req)
+	if err != nil {
+		logger.LogError("Error reading request body: " + err.Error())
+		r.fail(err)
+		return nil, err
+	}
+	if !r.bodyLogged {
+		r.bodyLogged = true
+		r.logBody(data)
+	}
+	return data, nil
+}
+
+// BodyAsString returns the body as a string, or "" if it could not be read.
 func (r *Request) BodyAsString() string {
-	return r.Body
+	data, _ := r.BodyBytes()
+	return string(data)
+}
+
+// Body returns the body as a string, or "" if it could not be read. It
+// replaces the Body field, which held the body read up front.
+//
+// Deprecated: Use BodyAsString, or BodyBytes to see the read error.
+func (r *Request) Body() string {
+	return r.BodyAsString()
+}
+
+// BodyReader streams the body without buffering it. Once the body has been
+// buffered it returns a new reader over the buffered copy instead.
+func (r *Request) BodyReader() io.Reader {
+	switch body := r.Req.Body.(type) {
+	case nil:
+		return http.NoBody
+	case *bufferedBody:
+		return bytes.NewReader(body.data)
+	default:
+		return body
+	}
+}
+
+// Err returns the first error recorded while handling the request, such as a
+// body that exceeded the size limit. If the handler returns without writing a
+// response, the App answers with the status code from StatusCode(Err()).
+func (r *Request) Err() error {
+	return r.err
+}
+
+func (r *Request) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
 }
@@ -9,11 +9,55 @@ import (
 
 type Response struct {
 	http.ResponseWriter
-	statusCodeWritten bool // This is to check if the status code has been written
+	rec    *RecordingWriter // records the status and bytes sent
+	req    *http.Request
+	stream *EventStream // the event stream started by SSE, if any
 }
 
 func NewResponse(w http.ResponseWriter) *Response {
-	return &Response{ResponseWriter: w}
+	rec := Record(w)
+	return &Response{ResponseWriter: rec, rec: rec}
+}
+
+// New wraps an incoming request and its response writer. Unlike NewResponse,
+// the Response can read the request's settings, such as the cookie keys.
+func New(w http.ResponseWriter, r *http.Request) (*Request, *Response) {
+	rec := Record(w)
+	return NewRequest(r), &Response{ResponseWriter: rec, rec: rec, req: r}
+}
+
+// Close stops the event stream started by SSE, if the handler left it open.
+// The App calls it when the handler returns.
+func (res *Response) Close() {
+	if res.stream != nil {
+		res.stream.Close()
+	}
+}
+
+// Write writes data to the client, sending a 200 status first if none was set.
+func (res *Response) Write(data []byte) (int, error) {
+	res.writeStatusIfNotWritten(http.StatusOK)
+	return res.ResponseWriter.Write(data)
+}
+
+// WriteHeader sends the status code once; later calls are ignored.
+func (res *Response) WriteHeader(statusCode int) {
+	res.writeStatusIfNotWritten(statusCode)
+}
+
+// Written reports whether the status code has already been sent.
+func (res *Response) Written() bool {
+	return res.rec.Written()
+}
+
+// StatusCode returns the status code sent, or 0 if none has been sent yet.
+func (res *Response) StatusCode() int {
+	return res.rec.Status()
+}
+
+// BytesWritten returns the number of body bytes sent.
+func (res *Response) BytesWritten() int64 {
+	return res.rec.BytesWritten()
 }
 
 func (res *Response) Send(data string) {
@@ -26,30 +70,55 @@ func (res *Response) Status(statusCode int) *Response {
 	return res
 }
 
+// JSON serializes data with the application/json codec and sends it.
 func (res *Response) JSON(data interface{}) {
-	res.Header().Set("Content-Type", "application/json")
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		logger.LogError("Error encoding JSON response: " + err.Error())
-		res.Status(http.StatusInternalServerError).JSONError("Error encoding JSON")
-		return
-	}
-	res.writeStatusIfNotWritten(http.StatusOK)
-	res.Write(jsonData)
+	res.Encode("application/json", data)
 }
 
-// New method to send standardized JSON error responses
+// JSONError sends {"error": message} as JSON. Without an earlier call to
+// Status, the status is 500 Internal Server Error. Use Problem for RFC 7807
+// error responses.
 func (res *Response) JSONError(message string) {
-	res.Header().Set("Content-Type", "application/json")
+	if !res.Written() {
+		res.Header().Set("Content-Type", "application/json")
+	}
+	res.writeStatusIfNotWritten(http.StatusInternalServerError)
 	errorData := map[string]string{"error": message}
 	jsonData, _ := json.Marshal(errorData)
 	res.Write(jsonData)
 }
 
+// Error sends err as RFC 7807 problem details with the status code from
+// StatusCode(err), as described by ProblemFor.
+func (res *Response) Error(err error) {
+	res.Problem(ProblemFor(err))
+}
+
+// Flush sends any buffered data to the client, so streamed responses reach it
+// as they are written over both HTTP/1.1 and HTTP/2.
+func (res *Response) Flush() {
+	res.writeStatusIfNotWritten(http.StatusOK)
+	if err := http.NewResponseController(res.ResponseWriter).Flush(); err != nil {
+		logger.LogError("Error flushing response: " + err.Error())
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController.
+func (res *Response) Unwrap() http.ResponseWriter {
+	return res.ResponseWriter
+}
+
+// config returns the settings of the request being answered.
+func (res *Response) config() *Config {
+	if res.req == nil {
+		return &defaultConfig
+	}
+	return ConfigFromContext(res.req.Context())
+}
+
 // Helper method to write status code if not already written
 func (res *Response) writeStatusIfNotWritten(statusCode int) {
-	if !res.statusCodeWritten {
-		res.WriteHeader(statusCode)
-		res.statusCodeWritten = true
+	if !res.rec.Written() {
+		res.rec.WriteHeader(statusCode)
 	}
 }
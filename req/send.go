@@ -0,0 +1,247 @@
+package req
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BrunoCiccarino/GopherLight/logger"
+)
+
+// Redirect sends the client to url, which may be relative to the request
+// path. A code of 0 picks 302 Found for GET and HEAD requests and 303 See
+// Other for anything else, so a browser follows up a form post with a GET.
+// Use 301 or 308 for permanent moves and 307 to repeat the same method.
+func (res *Response) Redirect(url string, code int) {
+	if code == 0 {
+		code = http.StatusFound
+		if res.req != nil && res.req.Method != http.MethodGet && res.req.Method != http.MethodHead {
+			code = http.StatusSeeOther
+		}
+	}
+	if code < 300 || code > 399 {
+		logger.LogError("Invalid redirect status " + strconv.Itoa(code))
+		res.Error(NewProblem(http.StatusInternalServerError, ""))
+		return
+	}
+	if res.Written() {
+		logger.LogError("Redirect to " + url + " after the response was written")
+		return
+	}
+	if res.req == nil {
+		res.Header().Set("Location", url)
+		res.writeStatusIfNotWritten(code)
+		return
+	}
+	http.Redirect(res, res.req, url, code)
+}
+
+// NoContent sends 204 No Content.
+func (res *Response) NoContent() {
+	res.writeStatusIfNotWritten(http.StatusNoContent)
+}
+
+// File sends the file at name. Range requests and If-Modified-Since,
+// If-None-Match and related headers are honoured, and the Content-Type is
+// taken from the extension or sniffed from the content. A missing file or a
+// directory gets 404 and an unreadable one 403. name is used as given, so
+// never build it from unchecked request input.
+func (res *Response) File(name string) {
+	f, err := os.Open(name)
+	if err != nil {
+		res.fileError(name, err)
+		return
+	}
+	defer f.Close()
+	res.serveFile(name, f)
+}
+
+// FileFS is File for a file in fsys, such as an embed.FS.
+func (res *Response) FileFS(fsys fs.FS, name string) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		res.fileError(name, err)
+		return
+	}
+	defer f.Close()
+	res.serveFile(name, f)
+}
+
+func (res *Response) serveFile(name string, f fs.File) {
+	info, err := f.Stat()
+	if err != nil {
+		res.fileError(name, err)
+		return
+	}
+	if info.IsDir() {
+		res.fileError(name, fs.ErrNotExist)
+		return
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			res.fileError(name, err)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+	http.ServeContent(res, res.request(), info.Name(), info.ModTime(), content)
+}
+
+// Download sends the file at name as an attachment saved as filename. A
+// filename outside ASCII is sent with the RFC 6266 filename* parameter, plus
+// an ASCII fallback for older clients. An empty filename uses the base name
+// of the file.
+func (res *Response) Download(name, filename string) {
+	if filename == "" {
+		filename = filepath.Base(name)
+	}
+	res.Header().Set("Content-Disposition", ContentDisposition("attachment", filename))
+	res.File(name)
+}
+
+// ContentDisposition formats a Content-Disposition header value of the given
+// type ("attachment" or "inline") for filename, following RFC 6266.
+func ContentDisposition(dispositionType, filename string) string {
+	var fallback, encoded strings.Builder
+	ascii := true
+	for i := 0; i < len(filename); i++ {
+		c := filename[i]
+		switch {
+		case c >= 0x80 || c < 0x20 || c == 0x7f:
+			ascii = false
+			// Replace each character once, skipping UTF-8 continuation bytes.
+			if c&0xc0 != 0x80 {
+				fallback.WriteByte('_')
+			}
+		case c == '"' || c == '\\':
+			fallback.WriteByte('\\')
+			fallback.WriteByte(c)
+		default:
+			fallback.WriteByte(c)
+		}
+
+		if isAttrChar(c) {
+			encoded.WriteByte(c)
+		} else {
+			encoded.WriteByte('%')
+			encoded.WriteByte("0123456789ABCDEF"[c>>4])
+			encoded.WriteByte("0123456789ABCDEF"[c&0x0f])
+		}
+	}
+
+	value := dispositionType + `; filename="` + fallback.String() + `"`
+	if !ascii {
+		value += "; filename*=UTF-8''" + encoded.String()
+	}
+	return value
+}
+
+// isAttrChar reports whether c may appear unencoded in an RFC 5987 value.
+func isAttrChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
+
+// Stream copies src to the client as it is read. Data is flushed at least
+// every flushInterval, or after every read when flushInterval is 0, so the
+// client sees it while src is still being produced. Stream stops when src
+// is exhausted, the client goes away or writing fails, and returns the
+// error that stopped it, or nil at the end of src.
+func (res *Response) Stream(src io.Reader, flushInterval time.Duration) error {
+	ctx := res.request().Context()
+	var mu sync.Mutex
+	dirty := false
+
+	if flushInterval > 0 {
+		ticker := time.NewTicker(flushInterval)
+		stop := make(chan struct{})
+		defer func() {
+			ticker.Stop()
+			close(stop)
+		}()
+		go func() {
+			for {
+				select {
+				case <-ticker.C:
+					mu.Lock()
+					if dirty {
+						res.Flush()
+						dirty = false
+					}
+					mu.Unlock()
+				case <-stop:
+					return
+				}
+			}
+		}()
+	}
+
+	buf := make([]byte, 32<<10)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			mu.Lock()
+			_, err := res.Write(buf[:n])
+			dirty = err == nil
+			if err == nil && flushInterval == 0 {
+				res.Flush()
+				dirty = false
+			}
+			mu.Unlock()
+			if err != nil {
+				return err
+			}
+		}
+		if readErr != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			if dirty || !res.Written() {
+				res.Flush()
+			}
+			if errors.Is(readErr, io.EOF) {
+				return nil
+			}
+			return readErr
+		}
+	}
+}
+
+// request returns the request being answered, or an empty GET request for a
+// Response created without one.
+func (res *Response) request() *http.Request {
+	if res.req == nil {
+		return (&http.Request{Method: http.MethodGet, Header: http.Header{}}).WithContext(context.Background())
+	}
+	return res.req
+}
+
+func (res *Response) fileError(name string, err error) {
+	res.Header().Del("Content-Disposition")
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		res.Error(NewProblem(http.StatusNotFound, ""))
+	case errors.Is(err, fs.ErrPermission):
+		res.Error(NewProblem(http.StatusForbidden, ""))
+	default:
+		logger.LogError("Error sending file " + name + ": " + err.Error())
+		res.Error(NewProblem(http.StatusInternalServerError, ""))
+	}
+}
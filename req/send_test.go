@@ -0,0 +1,179 @@
+package req
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestResponseRedirect(t *testing.T) {
+	tests := []struct {
+		method string
+		code   int
+		want   int
+	}{
+		{"GET", 0, http.StatusFound},
+		{"POST", 0, http.StatusSeeOther},
+		{"POST", http.StatusTemporaryRedirect, http.StatusTemporaryRedirect},
+		{"GET", http.StatusMovedPermanently, http.StatusMovedPermanently},
+	}
+
+	for _, tt := range tests {
+		_, res, rec := NewTestRequest(tt.method, "/old/page", nil)
+		res.Redirect("../new", tt.code)
+
+		if rec.Code != tt.want {
+			t.Fatalf("%s %d: expected status %d, got %d", tt.method, tt.code, tt.want, rec.Code)
+		}
+		if loc := rec.Header().Get("Location"); loc != "/new" {
+			t.Fatalf("Expected Location '/new', got '%s'", loc)
+		}
+	}
+
+	_, res, rec := NewTestRequest("GET", "/", nil)
+	res.Redirect("/elsewhere", http.StatusOK)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected a non-redirect status to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestResponseNoContent(t *testing.T) {
+	_, res, rec := NewTestRequest("DELETE", "/users/1", nil)
+	res.NoContent()
+
+	if rec.Code != http.StatusNoContent || rec.Body.Len() != 0 {
+		t.Fatalf("Expected empty 204, got %d '%s'", rec.Code, rec.Body.String())
+	}
+}
+
+func TestResponseFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "hello.txt")
+	os.WriteFile(name, []byte("hello, world"), 0o644)
+
+	_, res, rec := NewTestRequest("GET", "/hello.txt", nil)
+	res.File(name)
+	if rec.Code != http.StatusOK || rec.Body.String() != "hello, world" {
+		t.Fatalf("Expected file contents, got %d '%s'", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Fatalf("Expected text/plain, got %s", ct)
+	}
+
+	r, res, rec := NewTestRequest("GET", "/hello.txt", nil)
+	r.Req.Header.Set("Range", "bytes=7-")
+	res.File(name)
+	if rec.Code != http.StatusPartialContent || rec.Body.String() != "world" {
+		t.Fatalf("Expected partial content, got %d '%s'", rec.Code, rec.Body.String())
+	}
+
+	info, _ := os.Stat(name)
+	r, res, rec = NewTestRequest("GET", "/hello.txt", nil)
+	r.Req.Header.Set("If-Modified-Since", info.ModTime().Add(time.Second).UTC().Format(http.TimeFormat))
+	res.File(name)
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("Expected 304 Not Modified, got %d", rec.Code)
+	}
+
+	_, res, rec = NewTestRequest("GET", "/missing", nil)
+	res.File(filepath.Join(t.TempDir(), "missing.txt"))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for a missing file, got %d", rec.Code)
+	}
+}
+
+func TestResponseFileFS(t *testing.T) {
+	fsys := fstest.MapFS{"static/app.css": {Data: []byte("body{}")}}
+
+	_, res, rec := NewTestRequest("GET", "/app.css", nil)
+	res.FileFS(fsys, "static/app.css")
+	if rec.Body.String() != "body{}" || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/css") {
+		t.Fatalf("Expected CSS file, got %s '%s'", rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+
+	_, res, rec = NewTestRequest("GET", "/static", nil)
+	res.FileFS(fsys, "static")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for a directory, got %d", rec.Code)
+	}
+}
+
+func TestResponseDownload(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "report.csv")
+	os.WriteFile(name, []byte("a,b\n"), 0o644)
+
+	_, res, rec := NewTestRequest("GET", "/report", nil)
+	res.Download(name, "")
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="report.csv"` {
+		t.Fatalf("Unexpected Content-Disposition: %s", cd)
+	}
+	if rec.Body.String() != "a,b\n" {
+		t.Fatalf("Expected file contents, got '%s'", rec.Body.String())
+	}
+}
+
+func TestContentDisposition(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"plain.txt", `attachment; filename="plain.txt"`},
+		{`say "hi".txt`, `attachment; filename="say \"hi\".txt"`},
+		{"€ rates.pdf", `attachment; filename="_ rates.pdf"; filename*=UTF-8''%E2%82%AC%20rates.pdf`},
+	}
+
+	for _, tt := range tests {
+		if got := ContentDisposition("attachment", tt.filename); got != tt.want {
+			t.Errorf("ContentDisposition(%q) = %s, want %s", tt.filename, got, tt.want)
+		}
+	}
+}
+
+// flushRecorder counts the flushes that reach the client.
+type flushRecorder struct {
+	http.ResponseWriter
+	flushes int
+}
+
+func (f *flushRecorder) Flush() { f.flushes++ }
+
+func TestResponseStream(t *testing.T) {
+	r, _, rec := NewTestRequest("GET", "/events", nil)
+	w := &flushRecorder{ResponseWriter: rec}
+	_, res := New(w, r.Req)
+
+	pr, pw := io.Pipe()
+	go func() {
+		for _, chunk := range []string{"one\n", "two\n", "three\n"} {
+			pw.Write([]byte(chunk))
+		}
+		pw.Close()
+	}()
+
+	if err := res.Stream(pr, 0); err != nil {
+		t.Fatalf("Stream returned error: %v", err)
+	}
+	if rec.Body.String() != "one\ntwo\nthree\n" {
+		t.Fatalf("Unexpected streamed body: '%s'", rec.Body.String())
+	}
+	if w.flushes < 3 {
+		t.Fatalf("Expected a flush per chunk, got %d", w.flushes)
+	}
+}
+
+func TestResponseStreamPeriodicFlush(t *testing.T) {
+	r, _, rec := NewTestRequest("GET", "/events", nil)
+	w := &flushRecorder{ResponseWriter: rec}
+	_, res := New(w, r.Req)
+
+	if err := res.Stream(strings.NewReader("all at once"), time.Hour); err != nil {
+		t.Fatalf("Stream returned error: %v", err)
+	}
+	if rec.Body.String() != "all at once" || w.flushes != 1 {
+		t.Fatalf("Expected one final flush, got %d flushes and '%s'", w.flushes, rec.Body.String())
+	}
+}
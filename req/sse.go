@@ -0,0 +1,170 @@
+package req
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultKeepAlive is how often an event stream sends a comment to keep
+// idle connections and proxies from timing out.
+const DefaultKeepAlive = 15 * time.Second
+
+// ErrStreamClosed is returned by EventStream methods once the client has
+// gone away, the server is shutting down or Close was called.
+var ErrStreamClosed = errors.New("event stream closed")
+
+// EventStream writes Server-Sent Events. Its methods are safe for concurrent
+// use. The handler must not return until it is done with the stream; routes
+// registered on an App close it when the handler returns.
+type EventStream struct {
+	res         *Response
+	mu          sync.Mutex
+	err         error
+	keepAlive   *time.Ticker
+	closed      chan struct{}
+	closeOnce   sync.Once
+	done        chan struct{}
+	lastEventID string
+}
+
+// SSE starts a text/event-stream response and returns the stream to send
+// events on. Comments are sent every DefaultKeepAlive until KeepAlive
+// changes it. The stream's Done channel is closed when the client
+// disconnects or the App's server starts shutting down. Calling SSE again
+// on the same response returns the same stream.
+func (res *Response) SSE() *EventStream {
+	if res.stream != nil {
+		return res.stream
+	}
+	r := res.request()
+	// A stream stays open for as long as the client listens.
+	ReleaseTimeout(r)
+	s := &EventStream{
+		res:         res,
+		keepAlive:   time.NewTicker(DefaultKeepAlive),
+		closed:      make(chan struct{}),
+		done:        make(chan struct{}),
+		lastEventID: r.Header.Get("Last-Event-ID"),
+	}
+	res.stream = s
+
+	h := res.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	h.Del("Content-Length")
+	res.writeStatusIfNotWritten(http.StatusOK)
+	res.Flush()
+
+	shutdown := res.config().Shutdown
+	go func() {
+		defer s.keepAlive.Stop()
+		defer close(s.done)
+		for {
+			select {
+			case <-s.keepAlive.C:
+				s.Comment("keep-alive")
+			case <-r.Context().Done():
+				return
+			case <-shutdown:
+				return
+			case <-s.closed:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// LastEventID returns the Last-Event-ID header a reconnecting client sends,
+// so the handler can resume after the last event it received.
+func (s *EventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Send sends an event. event and id may be empty; data may span several
+// lines. The client stores a non-empty id and sends it back as Last-Event-ID
+// when it reconnects.
+func (s *EventStream) Send(event, id, data string) error {
+	if strings.ContainsAny(event, "\r\n") || strings.ContainsAny(id, "\r\n\x00") {
+		return errors.New("event stream: event and id must be a single line")
+	}
+	var b strings.Builder
+	if event != "" {
+		b.WriteString("event: " + event + "\n")
+	}
+	if id != "" {
+		b.WriteString("id: " + id + "\n")
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+// Comment sends a comment line, which clients ignore.
+func (s *EventStream) Comment(text string) error {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r", ""), "\n") {
+		b.WriteString(": " + line + "\n")
+	}
+	b.WriteString("\n")
+	return s.write(b.String())
+}
+
+// Retry tells the client how long to wait before reconnecting.
+func (s *EventStream) Retry(d time.Duration) error {
+	return s.write("retry: " + strconv.FormatInt(d.Milliseconds(), 10) + "\n\n")
+}
+
+// KeepAlive changes how often keep-alive comments are sent. An interval of
+// zero or less stops them.
+func (s *EventStream) KeepAlive(interval time.Duration) {
+	if interval <= 0 {
+		s.keepAlive.Stop()
+		return
+	}
+	s.keepAlive.Reset(interval)
+}
+
+// Done returns a channel that is closed when the client disconnects, the
+// server starts shutting down or Close is called.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close stops the stream. Later sends return ErrStreamClosed.
+func (s *EventStream) Close() {
+	s.closeOnce.Do(func() { close(s.closed) })
+	<-s.done
+}
+
+func (s *EventStream) write(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	select {
+	case <-s.done:
+		s.err = ErrStreamClosed
+		return s.err
+	case <-s.closed:
+		s.err = ErrStreamClosed
+		return s.err
+	default:
+	}
+	if _, err := s.res.Write([]byte(text)); err != nil {
+		s.err = err
+		return err
+	}
+	s.res.Flush()
+	return nil
+}
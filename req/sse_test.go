@@ -0,0 +1,101 @@
+package req
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventStreamSend(t *testing.T) {
+	r, res, rec := NewTestRequest("GET", "/events", nil)
+	r.Req.Header.Set("Last-Event-ID", "41")
+
+	stream := res.SSE()
+	if stream.LastEventID() != "41" {
+		t.Fatalf("Expected Last-Event-ID 41, got '%s'", stream.LastEventID())
+	}
+	stream.Retry(3 * time.Second)
+	stream.Send("update", "42", "line one\nline two")
+	stream.Send("", "", "plain")
+	if err := stream.Send("bad\nevent", "", "x"); err == nil {
+		t.Fatal("Expected an error for a multi-line event name")
+	}
+	stream.Close()
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %s", ct)
+	}
+	want := "retry: 3000\n\nevent: update\nid: 42\ndata: line one\ndata: line two\n\ndata: plain\n\n"
+	if rec.Body.String() != want {
+		t.Fatalf("Unexpected stream:\n%q\nwant\n%q", rec.Body.String(), want)
+	}
+	if err := stream.Send("late", "", "x"); err != ErrStreamClosed {
+		t.Fatalf("Expected ErrStreamClosed after Close, got %v", err)
+	}
+}
+
+func TestSSEReturnsOpenStream(t *testing.T) {
+	_, res, rec := NewTestRequest("GET", "/events", nil)
+	first := res.SSE()
+	second := res.SSE()
+	if first != second {
+		t.Fatal("Expected a second SSE call to return the open stream")
+	}
+	second.Send("", "", "once")
+	res.Close()
+
+	select {
+	case <-first.Done():
+	default:
+		t.Fatal("Expected closing the response to stop the stream")
+	}
+	if rec.Body.String() != "data: once\n\n" {
+		t.Fatalf("Unexpected stream: %q", rec.Body.String())
+	}
+}
+
+func TestEventStreamKeepAlive(t *testing.T) {
+	_, res, rec := NewTestRequest("GET", "/events", nil)
+	stream := res.SSE()
+	stream.KeepAlive(10 * time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	stream.Close()
+
+	if !strings.Contains(rec.Body.String(), ": keep-alive\n\n") {
+		t.Fatalf("Expected keep-alive comments, got %q", rec.Body.String())
+	}
+}
+
+func TestEventStreamStopsOnDisconnect(t *testing.T) {
+	r, res, _ := NewTestRequest("GET", "/events", nil)
+	ctx, cancel := context.WithCancel(r.Context())
+	res.req = r.Req.WithContext(ctx)
+
+	stream := res.SSE()
+	cancel()
+
+	select {
+	case <-stream.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Expected the stream to end when the client disconnects")
+	}
+	if err := stream.Send("", "", "gone"); err != ErrStreamClosed {
+		t.Fatalf("Expected ErrStreamClosed, got %v", err)
+	}
+}
+
+func TestEventStreamReleasesTimeout(t *testing.T) {
+	released := false
+	r, _, rec := NewTestRequest("GET", "/events", nil)
+	ctx := ContextWithTimeoutRelease(r.Req.Context(), func() bool {
+		released = true
+		return true
+	})
+	_, res := New(rec, r.Req.WithContext(ctx))
+
+	res.SSE().Close()
+	if !released {
+		t.Fatal("Expected SSE to release the request timeout")
+	}
+}
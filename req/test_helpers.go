@@ -8,9 +8,8 @@ import (
 )
 
 func NewTestRequest(method, target string, body io.Reader) (*Request, *Response, *httptest.ResponseRecorder) {
-	httpReq := httptest.NewRequest(method, target, body)
+	httpReq := WithLocals(httptest.NewRequest(method, target, body))
 	httpRes := httptest.NewRecorder()
-	req := NewRequest(httpReq)
-	res := NewResponse(httpRes)
+	req, res := New(httpRes, httpReq)
 	return req, res, httpRes
 }
@@ -0,0 +1,23 @@
+package req
+
+import "context"
+
+type timeoutReleaseKey struct{}
+
+// ContextWithTimeoutRelease returns a copy of ctx carrying release, which
+// lifts the deadline a timeout middleware put on the request. release
+// reports false when the request has already timed out.
+func ContextWithTimeoutRelease(ctx context.Context, release func() bool) context.Context {
+	return context.WithValue(ctx, timeoutReleaseKey{}, release)
+}
+
+// ReleaseTimeout lifts the deadline a timeout middleware put on r, for
+// responses that are long-lived by design such as event streams. It reports
+// false when the request has already timed out, and true when there was no
+// deadline to lift.
+func ReleaseTimeout(r ContextCarrier) bool {
+	if release, ok := r.Context().Value(timeoutReleaseKey{}).(func() bool); ok {
+		return release()
+	}
+	return true
+}
@@ -0,0 +1,311 @@
+package req
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+)
+
+// DefaultUploadMemoryThreshold is the size above which uploaded files are
+// spooled to a temporary file instead of kept in memory.
+const DefaultUploadMemoryThreshold int64 = 1 << 20
+
+// sniffLen is the number of bytes http.DetectContentType looks at.
+const sniffLen = 512
+
+// UploadConfig limits multipart file uploads. Zero values use the defaults.
+type UploadConfig struct {
+	// MemoryThreshold is the size above which a part is spooled to disk.
+	// Defaults to DefaultUploadMemoryThreshold. It also caps non-file fields.
+	MemoryThreshold int64
+
+	// MaxFileSize limits each file. Zero means no limit beyond the body size limit.
+	MaxFileSize int64
+
+	// MaxTotalSize limits all files together. Zero means no limit beyond the
+	// body size limit.
+	MaxTotalSize int64
+
+	// AllowedTypes lists the media types accepted, such as "image/png" or
+	// "image/*". Types are sniffed from the content, not taken from the client.
+	// Empty allows any type.
+	AllowedTypes []string
+
+	// TempDir is where large parts are spooled. Defaults to os.TempDir().
+	TempDir string
+}
+
+// UploadError is returned when an upload breaks the UploadConfig limits or
+// the multipart body is malformed.
+type UploadError struct {
+	Field   string
+	Message string
+	Status  int
+}
+
+func (e *UploadError) Error() string {
+	if e.Field == "" {
+		return "upload: " + e.Message
+	}
+	return "upload " + e.Field + ": " + e.Message
+}
+
+// StatusCode returns 400, 413 or 415 depending on the failure.
+func (e *UploadError) StatusCode() int {
+	return e.Status
+}
+
+// UploadedFile is a file part of a multipart request. Small files are held in
+// memory and large ones in a temporary file that is removed when the request
+// ends.
+type UploadedFile struct {
+	// Field is the form field name.
+	Field string
+	// Filename is the name sent by the client. Do not trust it as a path.
+	Filename string
+	// Header holds the part headers.
+	Header textproto.MIMEHeader
+	// Size is the file size in bytes.
+	Size int64
+	// ContentType is the media type sniffed from the content.
+	ContentType string
+
+	data []byte
+	path string
+}
+
+// Open returns a reader over the file contents.
+func (f *UploadedFile) Open() (io.ReadCloser, error) {
+	if f.path != "" {
+		return os.Open(f.path)
+	}
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+// multipartForm holds the parsed parts of a multipart request.
+type multipartForm struct {
+	values url.Values
+	files  []*UploadedFile
+	err    error
+}
+
+// FormFile returns the first file uploaded in field. It returns
+// http.ErrMissingFile when there is none.
+func (r *Request) FormFile(field string) (*UploadedFile, error) {
+	form := r.multipart()
+	if form.err != nil {
+		return nil, form.err
+	}
+	for _, f := range form.files {
+		if f.Field == field {
+			return f, nil
+		}
+	}
+	return nil, http.ErrMissingFile
+}
+
+// Files returns every file uploaded in a multipart request.
+func (r *Request) Files() ([]*UploadedFile, error) {
+	form := r.multipart()
+	return form.files, form.err
+}
+
+// Close removes the temporary files of spooled uploads. The App calls it when
+// the handler returns.
+func (r *Request) Close() error {
+	if r.form == nil {
+		return nil
+	}
+	var errs []error
+	for _, f := range r.form.files {
+		if f.path != "" {
+			if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			f.path = ""
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// multipart parses the body as multipart/form-data on first use.
+func (r *Request) multipart() *multipartForm {
+	if r.form != nil {
+		return r.form
+	}
+	r.form = &multipartForm{values: url.Values{}}
+
+	mediaType, params, err := mime.ParseMediaType(r.Req.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/form-data" || params["boundary"] == "" {
+		r.form.err = &UnsupportedMediaTypeError{ContentType: mediaType}
+		r.fail(r.form.err)
+		return r.form
+	}
+
+	cfg := ConfigFromContext(r.Req.Context()).Uploads
+	if cfg.MemoryThreshold <= 0 {
+		cfg.MemoryThreshold = DefaultUploadMemoryThreshold
+	}
+
+	if err := r.readParts(multipart.NewReader(r.BodyReader(), params["boundary"]), &cfg); err != nil {
+		r.form.err = err
+		r.fail(err)
+	}
+	return r.form
+}
+
+func (r *Request) readParts(mr *multipart.Reader, cfg *UploadConfig) error {
+	var total int64
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return uploadReadError("", err)
+		}
+
+		field := part.FormName()
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, cfg.MemoryThreshold+1))
+			part.Close()
+			if err != nil {
+				return uploadReadError(field, err)
+			}
+			if int64(len(value)) > cfg.MemoryThreshold {
+				return &UploadError{Field: field, Message: "field value too large", Status: http.StatusRequestEntityTooLarge}
+			}
+			r.form.values.Add(field, string(value))
+			continue
+		}
+
+		remaining := int64(-1)
+		if cfg.MaxTotalSize > 0 {
+			remaining = cfg.MaxTotalSize - total
+		}
+		file, err := spool(part, cfg, remaining)
+		part.Close()
+		if file != nil {
+			// Track the file before checking errors so Close removes it.
+			r.form.files = append(r.form.files, file)
+		}
+		if err != nil {
+			return err
+		}
+		total += file.Size
+	}
+}
+
+// spool reads a file part, sniffing its type and moving it to a temporary
+// file once it grows past the memory threshold. remaining is what is left of
+// MaxTotalSize, or negative when there is no total limit.
+func spool(part *multipart.Part, cfg *UploadConfig, remaining int64) (*UploadedFile, error) {
+	field := part.FormName()
+	file := &UploadedFile{Field: field, Filename: part.FileName(), Header: part.Header}
+
+	// The file may hold at most maxSize bytes, the smaller of MaxFileSize and
+	// the remaining total, so an oversized part is cut off before more than
+	// that reaches memory or disk. A negative maxSize means no limit.
+	maxSize, tooLarge := int64(-1), error(nil)
+	if cfg.MaxFileSize > 0 {
+		maxSize, tooLarge = cfg.MaxFileSize, fileTooLarge(field, cfg.MaxFileSize)
+	}
+	if remaining >= 0 && (maxSize < 0 || remaining < maxSize) {
+		maxSize, tooLarge = remaining, totalTooLarge(field, cfg.MaxTotalSize)
+	}
+
+	limit := cfg.MemoryThreshold
+	if maxSize >= 0 && maxSize < limit {
+		limit = maxSize
+	}
+	var buf bytes.Buffer
+	n, err := io.CopyN(&buf, part, limit+1)
+	if err != nil && err != io.EOF {
+		return nil, uploadReadError(field, err)
+	}
+
+	file.ContentType, _, _ = mime.ParseMediaType(http.DetectContentType(buf.Bytes()[:min(buf.Len(), sniffLen)]))
+	if !allowedType(cfg.AllowedTypes, file.ContentType) {
+		return nil, &UploadError{Field: field, Message: "file type " + file.ContentType + " is not allowed", Status: http.StatusUnsupportedMediaType}
+	}
+
+	if n <= limit {
+		file.data = buf.Bytes()
+		file.Size = n
+		return file, nil
+	}
+	if maxSize >= 0 && n > maxSize {
+		return nil, tooLarge
+	}
+
+	tmp, err := os.CreateTemp(cfg.TempDir, "gopherlight-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer tmp.Close()
+	file.path = tmp.Name()
+
+	src := io.MultiReader(&buf, part)
+	if maxSize < 0 {
+		file.Size, err = io.Copy(tmp, src)
+		if err != nil {
+			return file, uploadReadError(field, err)
+		}
+		return file, nil
+	}
+
+	file.Size, err = io.CopyN(tmp, src, maxSize)
+	if err == io.EOF {
+		return file, nil
+	}
+	if err != nil {
+		return file, uploadReadError(field, err)
+	}
+	// maxSize bytes are on disk; one more byte means the file is too large.
+	var extra [1]byte
+	switch _, err := io.ReadFull(src, extra[:]); err {
+	case nil:
+		return file, tooLarge
+	case io.EOF:
+		return file, nil
+	default:
+		return file, uploadReadError(field, err)
+	}
+}
+
+func allowedType(allowed []string, contentType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, pattern := range allowed {
+		if MatchMediaType(pattern, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+func fileTooLarge(field string, max int64) error {
+	return &UploadError{Field: field, Message: fmt.Sprintf("file exceeds %d bytes", max), Status: http.StatusRequestEntityTooLarge}
+}
+
+func totalTooLarge(field string, max int64) error {
+	return &UploadError{Field: field, Message: fmt.Sprintf("uploads exceed %d bytes in total", max), Status: http.StatusRequestEntityTooLarge}
+}
+
+// uploadReadError keeps body size errors intact and reports anything else as
+// a malformed upload.
+func uploadReadError(field string, err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return err
+	}
+	return &UploadError{Field: field, Message: "malformed multipart body: " + err.Error(), Status: http.StatusBadRequest}
+}
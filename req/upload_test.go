@@ -0,0 +1,198 @@
+package req
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+type uploadPart struct {
+	field, filename string
+	content         []byte
+}
+
+// uploadRequest builds a multipart request carrying parts and served with cfg.
+func uploadRequest(t *testing.T, cfg UploadConfig, parts ...uploadPart) *Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range parts {
+		var w io.Writer
+		var err error
+		if part.filename == "" {
+			w, err = mw.CreateFormField(part.field)
+		} else {
+			w, err = mw.CreateFormFile(part.field, part.filename)
+		}
+		if err != nil {
+			t.Fatalf("Failed to create part: %v", err)
+		}
+		w.Write(part.content)
+	}
+	mw.Close()
+
+	httpReq := httptest.NewRequest("POST", "/upload", &body)
+	httpReq.Header.Set("Content-Type", mw.FormDataContentType())
+	httpReq = httpReq.WithContext(ContextWithConfig(httpReq.Context(), &Config{Uploads: cfg}))
+	r := NewRequest(httpReq)
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestFormFileInMemory(t *testing.T) {
+	r := uploadRequest(t, UploadConfig{},
+		uploadPart{field: "note", content: []byte("hello")},
+		uploadPart{field: "avatar", filename: "a.png", content: append(pngHeader, "data"...)},
+	)
+
+	file, err := r.FormFile("avatar")
+	if err != nil {
+		t.Fatalf("FormFile returned error: %v", err)
+	}
+	if file.Filename != "a.png" || file.ContentType != "image/png" || file.Size != int64(len(pngHeader)+4) {
+		t.Fatalf("Unexpected file metadata: %+v", file)
+	}
+	if file.path != "" {
+		t.Fatal("Expected a small file to stay in memory")
+	}
+	if _, err := r.FormFile("missing"); !errors.Is(err, http.ErrMissingFile) {
+		t.Fatalf("Expected http.ErrMissingFile, got %v", err)
+	}
+}
+
+func TestLargeFileSpoolsToDiskAndIsRemoved(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 4096)
+	r := uploadRequest(t, UploadConfig{MemoryThreshold: 1024, TempDir: t.TempDir()},
+		uploadPart{field: "doc", filename: "big.txt", content: content},
+	)
+
+	files, err := r.Files()
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected one file, got %d (%v)", len(files), err)
+	}
+	path := files[0].path
+	if path == "" {
+		t.Fatal("Expected the file to be spooled to disk")
+	}
+
+	rc, err := files[0].Open()
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(data, content) {
+		t.Fatalf("Expected %d spooled bytes, got %d", len(content), len(data))
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected temp file to be removed, got %v", err)
+	}
+}
+
+func TestUploadLimits(t *testing.T) {
+	content := []byte(strings.Repeat("a", 2048))
+
+	tests := []struct {
+		name   string
+		cfg    UploadConfig
+		parts  []uploadPart
+		status int
+	}{
+		{
+			name:   "file too large",
+			cfg:    UploadConfig{MemoryThreshold: 512, MaxFileSize: 1024, TempDir: t.TempDir()},
+			parts:  []uploadPart{{field: "f", filename: "f.txt", content: content}},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "total too large",
+			cfg:  UploadConfig{MaxTotalSize: 3000},
+			parts: []uploadPart{
+				{field: "a", filename: "a.txt", content: content},
+				{field: "b", filename: "b.txt", content: content},
+			},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "type not allowed",
+			cfg:    UploadConfig{AllowedTypes: []string{"image/*"}},
+			parts:  []uploadPart{{field: "f", filename: "f.png", content: content}},
+			status: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := uploadRequest(t, tt.cfg, tt.parts...)
+			_, err := r.Files()
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if got := StatusCode(r.Err()); got != tt.status {
+				t.Fatalf("Expected status %d, got %d (%v)", tt.status, got, err)
+			}
+		})
+	}
+}
+
+func TestTotalLimitStopsSpoolingOversizedFile(t *testing.T) {
+	const budget = 4096
+	r := uploadRequest(t, UploadConfig{MemoryThreshold: 512, MaxTotalSize: budget, TempDir: t.TempDir()},
+		uploadPart{field: "small", filename: "small.txt", content: bytes.Repeat([]byte("s"), 1000)},
+		uploadPart{field: "big", filename: "big.txt", content: bytes.Repeat([]byte("b"), 1<<20)},
+	)
+
+	if _, err := r.Files(); err == nil {
+		t.Fatal("Expected the total limit to be exceeded")
+	}
+	if got := StatusCode(r.Err()); got != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, got)
+	}
+
+	var written int64
+	for _, f := range r.form.files {
+		if f.path == "" {
+			written += int64(len(f.data))
+			continue
+		}
+		info, err := os.Stat(f.path)
+		if err != nil {
+			t.Fatalf("Failed to stat spooled file: %v", err)
+		}
+		written += info.Size()
+	}
+	if written > budget {
+		t.Fatalf("Expected at most %d bytes to be kept, got %d", budget, written)
+	}
+}
+
+func TestBindMultipartForm(t *testing.T) {
+	r := uploadRequest(t, UploadConfig{},
+		uploadPart{field: "name", content: []byte("Gopher")},
+		uploadPart{field: "age", content: []byte("13")},
+		uploadPart{field: "avatar", filename: "a.png", content: pngHeader},
+	)
+
+	var dst bindTarget
+	if err := r.Bind(&dst); err != nil {
+		t.Fatalf("Bind returned error: %v", err)
+	}
+	if dst.Name != "Gopher" || dst.Age != 13 {
+		t.Fatalf("Expected multipart fields to be bound, got %+v", dst)
+	}
+	if _, err := r.FormFile("avatar"); err != nil {
+		t.Fatalf("Expected file to remain available after Bind, got %v", err)
+	}
+}
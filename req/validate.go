@@ -0,0 +1,388 @@
+package req
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError describes a field that failed a validation rule.
+type ValidationError struct {
+	// Field is the path of the field, using its json tag name when it has one
+	// (e.g. "address.city" or "items[0].name").
+	Field string `json:"field"`
+	// Rule is the name of the failing rule, such as "required" or "max".
+	Rule string `json:"rule"`
+	// Param is the rule parameter, such as "120" for max=120.
+	Param string `json:"param,omitempty"`
+	// Message explains the failure.
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationErrors lists every failed rule. It is answered with
+// 422 Unprocessable Entity.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// StatusCode returns 422 Unprocessable Entity.
+func (e ValidationErrors) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// Validatable is implemented by structs with rules that tags cannot express.
+// Validate runs it after the tag rules; returning ValidationErrors adds them
+// to the list, and any other error is reported against the struct itself.
+type Validatable interface {
+	Validate() error
+}
+
+// FieldContext is passed to validation rules.
+type FieldContext struct {
+	// Field is the value being validated.
+	Field reflect.Value
+	// Parent is the struct holding the field, for cross-field rules.
+	Parent reflect.Value
+	// Param is the rule parameter, e.g. "a b" for oneof=a b.
+	Param string
+}
+
+// Sibling returns the field of Parent with the given Go name.
+func (fc FieldContext) Sibling(name string) (reflect.Value, bool) {
+	field := fc.Parent.FieldByName(name)
+	return field, field.IsValid()
+}
+
+// ValidationRule reports whether the field in fc is valid.
+type ValidationRule func(fc FieldContext) bool
+
+type registeredRule struct {
+	check   ValidationRule
+	message string
+}
+
+var (
+	rulesMu sync.RWMutex
+	rules   = map[string]registeredRule{}
+)
+
+// RegisterValidation adds a rule usable in `validate` tags. The message is
+// reported when the rule fails; "%s" in it is replaced by the rule parameter.
+// Registering an existing name replaces the rule.
+func RegisterValidation(name string, rule ValidationRule, message string) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules[name] = registeredRule{check: rule, message: message}
+}
+
+func init() {
+	RegisterValidation("required", func(fc FieldContext) bool { return !isEmpty(fc.Field) }, "is required")
+	RegisterValidation("min", func(fc FieldContext) bool { c := compareParam(fc); return c == 0 || c == 1 }, "must be at least %s")
+	RegisterValidation("max", func(fc FieldContext) bool { return compareParam(fc) <= 0 }, "must be at most %s")
+	RegisterValidation("len", func(fc FieldContext) bool { return compareParam(fc) == 0 }, "must have length %s")
+	RegisterValidation("gt", func(fc FieldContext) bool { return compareParam(fc) == 1 }, "must be greater than %s")
+	RegisterValidation("gte", func(fc FieldContext) bool { c := compareParam(fc); return c == 0 || c == 1 }, "must be at least %s")
+	RegisterValidation("lt", func(fc FieldContext) bool { return compareParam(fc) < 0 }, "must be less than %s")
+	RegisterValidation("lte", func(fc FieldContext) bool { return compareParam(fc) <= 0 }, "must be at most %s")
+	RegisterValidation("oneof", validateOneOf, "must be one of [%s]")
+	RegisterValidation("email", validateEmail, "must be a valid email address")
+	RegisterValidation("url", validateURL, "must be a valid URL")
+	RegisterValidation("eqfield", func(fc FieldContext) bool { return compareSibling(fc) == 0 }, "must equal %s")
+	RegisterValidation("nefield", func(fc FieldContext) bool { c := compareSibling(fc); return c == -1 || c == 1 }, "must not equal %s")
+	RegisterValidation("gtfield", func(fc FieldContext) bool { return compareSibling(fc) == 1 }, "must be greater than %s")
+	RegisterValidation("gtefield", func(fc FieldContext) bool { c := compareSibling(fc); return c == 0 || c == 1 }, "must be greater than or equal to %s")
+	RegisterValidation("ltfield", func(fc FieldContext) bool { return compareSibling(fc) == -1 }, "must be less than %s")
+	RegisterValidation("ltefield", func(fc FieldContext) bool { c := compareSibling(fc); return c == 0 || c == -1 }, "must be less than or equal to %s")
+	RegisterValidation("required_with", func(fc FieldContext) bool {
+		sibling, ok := fc.Sibling(fc.Param)
+		return !ok || isEmpty(sibling) || !isEmpty(fc.Field)
+	}, "is required when %s is set")
+	RegisterValidation("required_without", func(fc FieldContext) bool {
+		sibling, ok := fc.Sibling(fc.Param)
+		return ok && !isEmpty(sibling) || !isEmpty(fc.Field)
+	}, "is required when %s is not set")
+}
+
+// Validate checks the struct v (or pointer to struct) against its `validate`
+// tags, e.g. `validate:"required,min=1,max=120"`. Nested structs, pointers to
+// structs and slices of structs are validated too. The "omitempty" rule skips
+// the remaining rules when the field is empty. It returns ValidationErrors
+// listing every failure, or nil.
+func Validate(v interface{}) error {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("req: Validate requires a struct, got %s", value.Kind())
+	}
+
+	var errs ValidationErrors
+	validateStruct(value, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("validate") == "" {
+			validateStruct(fv, prefix, errs)
+			continue
+		}
+
+		path := prefix + fieldName(field)
+		if tag := field.Tag.Get("validate"); tag != "" && tag != "-" {
+			validateField(fv, v, path, tag, errs)
+		}
+		validateNested(fv, path, errs)
+	}
+
+	if v.CanAddr() {
+		if custom, ok := v.Addr().Interface().(Validatable); ok {
+			addCustomErrors(custom.Validate(), prefix, errs)
+		}
+	} else if custom, ok := v.Interface().(Validatable); ok {
+		addCustomErrors(custom.Validate(), prefix, errs)
+	}
+}
+
+func addCustomErrors(err error, prefix string, errs *ValidationErrors) {
+	if err == nil {
+		return
+	}
+	if list, ok := err.(ValidationErrors); ok {
+		for _, fieldErr := range list {
+			fieldErr.Field = prefix + fieldErr.Field
+			*errs = append(*errs, fieldErr)
+		}
+		return
+	}
+	*errs = append(*errs, ValidationError{Field: strings.TrimSuffix(prefix, "."), Rule: "custom", Message: err.Error()})
+}
+
+// validateNested descends into struct values held by a field.
+func validateNested(v reflect.Value, path string, errs *ValidationErrors) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			validateNested(v.Elem(), path, errs)
+		}
+	case reflect.Struct:
+		if v.Type() != timeType {
+			validateStruct(v, path+".", errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateNested(v.Index(i), path+"["+strconv.Itoa(i)+"]", errs)
+		}
+	}
+}
+
+func validateField(v, parent reflect.Value, path, tag string, errs *ValidationErrors) {
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if name == "" {
+			continue
+		}
+		if name == "omitempty" {
+			if isEmpty(v) {
+				return
+			}
+			continue
+		}
+
+		rulesMu.RLock()
+		registered, ok := rules[name]
+		rulesMu.RUnlock()
+		if !ok {
+			*errs = append(*errs, ValidationError{Field: path, Rule: name, Param: param, Message: "unknown validation rule " + strconv.Quote(name)})
+			continue
+		}
+
+		field := v
+		if name != "required" && field.Kind() == reflect.Pointer {
+			if field.IsNil() {
+				continue
+			}
+			field = field.Elem()
+		}
+		if !registered.check(FieldContext{Field: field, Parent: parent, Param: param}) {
+			message := registered.message
+			if strings.Contains(message, "%s") {
+				message = fmt.Sprintf(message, param)
+			}
+			*errs = append(*errs, ValidationError{Field: path, Rule: name, Param: param, Message: message})
+			if name == "required" {
+				return
+			}
+		}
+	}
+}
+
+// fieldName returns the name a client knows the field by: its json tag, or
+// another binding tag, or the Go field name.
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "query", "param", "header"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// compareParam compares the field against the rule parameter: numbers by
+// value, durations as durations, and strings, slices and maps by length.
+// It returns -1, 0 or 1, or 2 when they cannot be compared.
+func compareParam(fc FieldContext) int {
+	v := fc.Field
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(fc.Param)
+		if err != nil {
+			return 2
+		}
+		return compareFloat(float64(v.Int()), float64(d))
+	}
+
+	param, err := strconv.ParseFloat(fc.Param, 64)
+	if err != nil {
+		return 2
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return compareFloat(float64(utf8.RuneCountInString(v.String())), param)
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return compareFloat(float64(v.Len()), param)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareFloat(float64(v.Int()), param)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareFloat(float64(v.Uint()), param)
+	case reflect.Float32, reflect.Float64:
+		return compareFloat(v.Float(), param)
+	}
+	return 2
+}
+
+// compareSibling compares the field with the sibling named by the rule
+// parameter. It returns -1, 0 or 1, or 2 when they cannot be compared.
+func compareSibling(fc FieldContext) int {
+	other, ok := fc.Sibling(fc.Param)
+	if !ok {
+		return 2
+	}
+	if other.Kind() == reflect.Pointer {
+		if other.IsNil() {
+			return 2
+		}
+		other = other.Elem()
+	}
+	v := fc.Field
+	if v.Type() != other.Type() {
+		return 2
+	}
+
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Compare(other.Interface().(time.Time))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return strings.Compare(v.String(), other.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareFloat(float64(v.Int()), float64(other.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareFloat(float64(v.Uint()), float64(other.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return compareFloat(v.Float(), other.Float())
+	case reflect.Bool:
+		if v.Bool() == other.Bool() {
+			return 0
+		}
+	}
+	return 2
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func validateOneOf(fc FieldContext) bool {
+	var value string
+	switch fc.Field.Kind() {
+	case reflect.String:
+		value = fc.Field.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = strconv.FormatInt(fc.Field.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = strconv.FormatUint(fc.Field.Uint(), 10)
+	default:
+		return false
+	}
+	for _, option := range strings.Fields(fc.Param) {
+		if value == option {
+			return true
+		}
+	}
+	return false
+}
+
+func validateEmail(fc FieldContext) bool {
+	if fc.Field.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(fc.Field.String())
+	return err == nil && addr.Address == fc.Field.String()
+}
+
+func validateURL(fc FieldContext) bool {
+	if fc.Field.Kind() != reflect.String {
+		return false
+	}
+	u, err := url.ParseRequestURI(fc.Field.String())
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
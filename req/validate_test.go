@@ -0,0 +1,181 @@
+package req
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type signup struct {
+	Name     string    `json:"name" validate:"required,min=2,max=20"`
+	Age      int       `json:"age" validate:"min=1,max=120"`
+	Email    string    `json:"email" validate:"required,email"`
+	Plan     string    `json:"plan" validate:"oneof=free pro"`
+	Password string    `json:"password" validate:"required"`
+	Confirm  string    `json:"confirm" validate:"eqfield=Password"`
+	Website  string    `json:"website" validate:"omitempty,url"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end" validate:"gtfield=Start"`
+	Address  *address  `json:"address"`
+}
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+func validSignup() signup {
+	start := time.Now()
+	return signup{
+		Name:     "Gopher",
+		Age:      13,
+		Email:    "gopher@example.com",
+		Plan:     "pro",
+		Password: "secret",
+		Confirm:  "secret",
+		Start:    start,
+		End:      start.Add(time.Hour),
+		Address:  &address{City: "Lisbon"},
+	}
+}
+
+func TestValidatePasses(t *testing.T) {
+	s := validSignup()
+	if err := Validate(&s); err != nil {
+		t.Fatalf("Expected no validation errors, got %v", err)
+	}
+}
+
+func TestValidateListsEveryFailure(t *testing.T) {
+	s := validSignup()
+	s.Name = "G"
+	s.Age = 200
+	s.Email = "not-an-email"
+	s.Plan = "enterprise"
+	s.Confirm = "other"
+	s.Website = "nope"
+	s.End = s.Start.Add(-time.Hour)
+	s.Address.City = ""
+
+	err := Validate(&s)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Field+":"+e.Rule)
+	}
+	expected := []string{"name:min", "age:max", "email:email", "plan:oneof", "confirm:eqfield", "website:url", "end:gtfield", "address.city:required"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("Expected failures %v, got %v", expected, got)
+	}
+}
+
+type evenNumber struct {
+	Value int `json:"value" validate:"even"`
+}
+
+type dateRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (d dateRange) Validate() error {
+	if d.To-d.From > 30 {
+		return ValidationErrors{{Field: "to", Rule: "range", Message: "range must be at most 30 days"}}
+	}
+	return nil
+}
+
+func TestValidateUncomparableParams(t *testing.T) {
+	var s struct {
+		Count int       `validate:"min=abc"`
+		Name  string    `validate:"gte=x"`
+		When  time.Time `validate:"gt=5"`
+	}
+	s.Count, s.Name, s.When = 10, "Gopher", time.Now()
+
+	var errs ValidationErrors
+	if err := Validate(&s); !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("Expected every rule with an uncomparable parameter to fail, got %v", err)
+	}
+}
+
+func TestNeFieldFailsWhenSiblingCannotBeCompared(t *testing.T) {
+	var missing struct {
+		Old string
+		New string `validate:"nefield=Olde"`
+	}
+	missing.Old, missing.New = "a", "b"
+	if err := Validate(&missing); err == nil {
+		t.Fatal("Expected nefield with a missing sibling to fail")
+	}
+
+	var mismatched struct {
+		Old int
+		New string `validate:"nefield=Old"`
+	}
+	mismatched.Old, mismatched.New = 1, "b"
+	if err := Validate(&mismatched); err == nil {
+		t.Fatal("Expected nefield with a sibling of another type to fail")
+	}
+}
+
+func TestCustomValidators(t *testing.T) {
+	rulesMu.RLock()
+	saved := make(map[string]registeredRule, len(rules))
+	for name, rule := range rules {
+		saved[name] = rule
+	}
+	rulesMu.RUnlock()
+	t.Cleanup(func() {
+		rulesMu.Lock()
+		defer rulesMu.Unlock()
+		rules = saved
+	})
+
+	RegisterValidation("even", func(fc FieldContext) bool { return fc.Field.Int()%2 == 0 }, "must be even")
+
+	if err := Validate(evenNumber{Value: 3}); err == nil || !strings.Contains(err.Error(), "must be even") {
+		t.Fatalf("Expected custom rule failure, got %v", err)
+	}
+	if err := Validate(dateRange{From: 1, To: 60}); err == nil || !strings.Contains(err.Error(), "at most 30 days") {
+		t.Fatalf("Expected struct-level rule failure, got %v", err)
+	}
+}
+
+func TestBindValidationAnswers422(t *testing.T) {
+	httpReq := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"","age":0}`))
+	httpReq.Header.Set("Content-Type", "application/json")
+	r := NewRequest(httpReq)
+
+	var s signup
+	if err := r.Bind(&s); err == nil {
+		t.Fatal("Expected Bind to fail validation")
+	}
+
+	w := httptest.NewRecorder()
+	NewResponse(w).Error(r.Err())
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	if ct := w.Result().Header.Get("Content-Type"); ct != ProblemContentType {
+		t.Fatalf("Expected %s, got %s", ProblemContentType, ct)
+	}
+
+	var body struct {
+		Fields []ValidationError `json:"fields"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Error unmarshalling response body: %v", err)
+	}
+	if len(body.Fields) == 0 || body.Fields[0].Field != "name" || body.Fields[0].Rule != "required" {
+		t.Fatalf("Expected machine-readable field errors, got %+v", body.Fields)
+	}
+}
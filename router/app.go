@@ -2,6 +2,7 @@ package router
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,9 +11,12 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/BrunoCiccarino/GopherLight/clientip"
+	"github.com/BrunoCiccarino/GopherLight/health"
 	"github.com/BrunoCiccarino/GopherLight/logger"
 	"github.com/BrunoCiccarino/GopherLight/plugins"
 	"github.com/BrunoCiccarino/GopherLight/req"
+	"github.com/BrunoCiccarino/GopherLight/ws"
 )
 
 // Middleware defines a function signature for middleware.
@@ -20,19 +24,135 @@ type Middleware func(http.HandlerFunc) http.HandlerFunc
 
 // App represents the core web application structure, managing routes, middlewares, and plugins.
 type App struct {
-	root        *Node
-	middlewares []Middleware
-	plugins     []plugins.Plugin
+	root            *Node
+	middlewares     []Middleware
+	plugins         []plugins.Plugin
+	hooks           lifecycle
+	shutdownTimeout time.Duration
+	health          *health.Checker
+	maxBodySize     int64
+	maxDecompressed int64
+	config          req.Config
+	clientIP        *clientip.Resolver
 }
 
+// defaultShutdownTimeout is the grace period given to shutdown hooks and in-flight requests.
+const defaultShutdownTimeout = 30 * time.Second
+
 // NewApp creates a new App instance with an initialized root node.
 // Returns:
 //
 //	*App: A new App instance.
 func NewApp() *App {
-	return &App{
-		root: NewNode("/"),
+	a := &App{
+		root:            NewNode("/"),
+		shutdownTimeout: defaultShutdownTimeout,
+		maxBodySize:     req.DefaultMaxBodySize,
+		maxDecompressed: req.DefaultMaxDecompressedSize,
+	}
+	return a
+}
+
+// SetBodyLogPolicy enables or configures request body logging for every route.
+// Use req.LogBody to override the policy for a single route.
+// Args:
+//
+//	policy (req.BodyLogPolicy): The logging policy.
+func (a *App) SetBodyLogPolicy(policy req.BodyLogPolicy) {
+	a.config.BodyLog = policy
+}
+
+// SetUploadConfig sets the limits for multipart file uploads read with
+// FormFile and Files.
+// Args:
+//
+//	cfg (req.UploadConfig): The upload limits.
+func (a *App) SetUploadConfig(cfg req.UploadConfig) {
+	a.config.Uploads = cfg
+}
+
+// SetErrorHandler sets the function that answers errors returned by
+// handlers wrapped with req.HandleE and errors recorded while reading the
+// request, such as binding failures.
+// Args:
+//
+//	handler (req.ErrorHandler): The error handler, or nil for req.DefaultErrorHandler.
+func (a *App) SetErrorHandler(handler req.ErrorHandler) {
+	a.config.Errors = handler
+}
+
+// SetViews sets the renderer used by res.Render, usually a *view.Engine.
+// Args:
+//
+//	renderer (req.Renderer): The template renderer.
+func (a *App) SetViews(renderer req.Renderer) {
+	a.config.Views = renderer
+}
+
+// SetCookieKeys sets the secrets used by signed and encrypted cookies,
+// newest first. New cookies use the first key; the others still verify
+// cookies issued before a rotation.
+// Args:
+//
+//	secrets ([]byte): Random secrets of at least 32 bytes each.
+//
+// Returns:
+//
+//	error: An error if no secret is given or one is too short.
+func (a *App) SetCookieKeys(secrets ...[]byte) error {
+	keys, err := req.NewKeyring(secrets...)
+	if err != nil {
+		return err
 	}
+	a.config.Cookies = keys
+	return nil
+}
+
+// SetTrustedProxies sets the reverse proxies whose X-Forwarded-For, X-Real-IP
+// and Forwarded headers are believed when resolving the client IP. Until it is
+// called no proxy is trusted and the socket address is used.
+// Args:
+//
+//	cidrs (string): Proxy ranges such as "10.0.0.0/8", or single addresses.
+//
+// Returns:
+//
+//	error: An error if a range cannot be parsed.
+func (a *App) SetTrustedProxies(cidrs ...string) error {
+	resolver, err := clientip.NewResolver(cidrs...)
+	if err != nil {
+		return err
+	}
+	a.clientIP = resolver
+	return nil
+}
+
+// SetMaxDecompressedSize limits the size of gzip and deflate request bodies
+// once decompressed, so a small compressed body cannot expand without bound.
+// Reading past the limit answers with 413 Request Entity Too Large. A size of
+// zero or less disables the limit.
+// Args:
+//
+//	size (int64): The maximum decompressed body size in bytes.
+func (a *App) SetMaxDecompressedSize(size int64) {
+	a.maxDecompressed = size
+}
+
+// SetMaxBodySize limits the size of request bodies. Larger bodies are answered
+// with 413 Request Entity Too Large. A size of zero or less disables the limit.
+// Args:
+//
+//	size (int64): The maximum body size in bytes.
+func (a *App) SetMaxBodySize(size int64) {
+	a.maxBodySize = size
+}
+
+// SetShutdownTimeout sets the grace period for shutdown hooks and in-flight requests.
+// Args:
+//
+//	timeout (time.Duration): The maximum time shutdown may take.
+func (a *App) SetShutdownTimeout(timeout time.Duration) {
+	a.shutdownTimeout = timeout
 }
 
 // Use adds a middleware function to the App's middleware stack.
@@ -43,6 +163,21 @@ func (a *App) Use(mw Middleware) {
 	a.middlewares = append(a.middlewares, mw)
 }
 
+// EnableHealthChecks registers the /livez and /readyz endpoints and returns the
+// checker used to add named checks. Readiness starts failing as soon as Listen
+// receives a shutdown signal. Calling it again returns the same checker.
+// Returns:
+//
+//	*health.Checker: The application's health checker.
+func (a *App) EnableHealthChecks() *health.Checker {
+	if a.health == nil {
+		a.health = health.New()
+		a.Get("/livez", a.health.LivenessHandler)
+		a.Get("/readyz", a.health.ReadinessHandler)
+	}
+	return a.health
+}
+
 // AddPlugin adds a plugin to the App's plugin stack.
 // Args:
 //
@@ -51,10 +186,14 @@ func (a *App) AddPlugin(p plugins.Plugin) {
 	a.plugins = append(a.plugins, p)
 }
 
-// RegisterPlugins registers all plugins added to the App.
+// RegisterPlugins registers all plugins added to the App, including the
+// lifecycle hooks of plugins that implement plugins.LifecyclePlugin.
 func (a *App) RegisterPlugins() {
 	for _, plugin := range a.plugins {
 		plugin.Register(a.Route)
+		if lp, ok := plugin.(plugins.LifecyclePlugin); ok {
+			lp.RegisterHooks(a)
+		}
 	}
 }
 
@@ -62,15 +201,20 @@ func (a *App) RegisterPlugins() {
 // Args:
 //
 //	method (string): The HTTP method (e.g., "GET").
-//	path (string): The route path.
+//	path (string): The route path. Segments like ":id" capture path parameters.
 //	handler (req.Handler): The handler function for the route.
 func (a *App) Route(method, path string, handler req.Handler) {
 	segments := strings.Split(strings.Trim(path, "/"), "/")
 
 	var h http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
-		request := req.NewRequest(r)
-		response := req.NewResponse(w)
+		request, response := req.New(w, r)
+		defer request.Close()
+		defer response.Close()
 		handler(request, response)
+
+		if err := request.Err(); err != nil && !response.Written() {
+			req.HandleError(err, request, response)
+		}
 	}
 
 	for i := len(a.middlewares) - 1; i >= 0; i-- {
@@ -80,6 +224,25 @@ func (a *App) Route(method, path string, handler req.Handler) {
 	a.root.AddRoute(append([]string{method}, segments...), h)
 }
 
+// WebSocket registers a WebSocket endpoint. The handshake is completed
+// before handler runs, and the connection is closed when it returns.
+// Args:
+//
+//	path (string): The route path.
+//	handler (func(*ws.Conn)): Handles the connection.
+//	opts (...ws.Option): Upgrade options, such as ws.WithCORS.
+func (a *App) WebSocket(path string, handler func(conn *ws.Conn), opts ...ws.Option) {
+	a.Route(http.MethodGet, path, func(r *req.Request, res *req.Response) {
+		conn, err := ws.Upgrade(res, r.Req, opts...)
+		if err != nil {
+			logger.LogError("WebSocket upgrade failed for " + r.Req.URL.Path + ": " + err.Error())
+			return
+		}
+		defer conn.Close(ws.CloseNormal, "")
+		handler(conn)
+	})
+}
+
 // Get registers a handler for the GET HTTP method.
 // Args:
 //
@@ -167,12 +330,46 @@ func (a *App) Trace(path string, handler req.Handler) {
 //	w (http.ResponseWriter): The response writer.
 //	r (*http.Request): The incoming request.
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.serve(w, r, nil)
+}
+
+// serve dispatches a request like ServeHTTP. shutdown is closed when the
+// server handling the request starts shutting down; it is nil when the App is
+// used as a plain http.Handler.
+// Args:
+//
+//	w (http.ResponseWriter): The response writer.
+//	r (*http.Request): The incoming request.
+//	shutdown (<-chan struct{}): Closed when the server shuts down.
+func (a *App) serve(w http.ResponseWriter, r *http.Request, shutdown <-chan struct{}) {
+	cfg := a.config
+	cfg.Shutdown = shutdown
+	ctx := req.ContextWithConfig(r.Context(), &cfg)
+	if a.clientIP != nil {
+		ctx = clientip.ContextWithResolver(ctx, a.clientIP)
+	}
+	r = req.WithLocals(r.WithContext(ctx))
 	pathSegments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
 	fullPath := append([]string{r.Method}, pathSegments...)
 
-	handler, routeExists := a.root.FindRoute(fullPath)
+	handler, params, routeExists := a.root.Lookup(fullPath)
 
 	if routeExists {
+		for name, value := range params {
+			r.SetPathValue(name, value)
+		}
+		if a.maxBodySize > 0 && r.Body != nil {
+			if r.ContentLength > a.maxBodySize {
+				problem(w, r, http.StatusRequestEntityTooLarge, "The request body is too large.")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, a.maxBodySize)
+		}
+		if err := req.DecompressBody(r, a.maxDecompressed); err != nil {
+			w.Header().Set("Accept-Encoding", req.SupportedEncodings)
+			problem(w, r, http.StatusUnsupportedMediaType, err.Error())
+			return
+		}
 		handler(w, r)
 		return
 	}
@@ -182,12 +379,25 @@ func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		_, exists := a.root.FindRoute(alternatePath)
 		if exists {
 			w.Header().Set("Allow", strings.Join(allowedMethods(pathSegments, a.root), ", "))
-			http.Error(w, "405 Method Not Allowed", http.StatusMethodNotAllowed)
+			problem(w, r, http.StatusMethodNotAllowed, "The method "+r.Method+" is not allowed for this resource.")
 			return
 		}
 	}
 
-	http.NotFound(w, r)
+	problem(w, r, http.StatusNotFound, "No route matches the requested path.")
+}
+
+// problem answers a request the router cannot dispatch with an RFC 7807
+// problem details response.
+// Args:
+//
+//	w (http.ResponseWriter): The response writer.
+//	r (*http.Request): The incoming request.
+//	status (int): The HTTP status code.
+//	detail (string): An explanation of the problem.
+func problem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	_, res := req.New(w, r)
+	res.Problem(req.NewProblem(status, detail))
 }
 
 var httpMethods = map[string]struct{}{
@@ -224,41 +434,109 @@ func allowedMethods(pathSegments []string, root *Node) []string {
 }
 
 // Listen starts the HTTP server on the specified address and handles graceful shutdown.
+// Start hooks run before the listener is bound and ready hooks right after it.
+// On SIGUSR2 it starts a new copy of the binary and hands it the listening socket.
+// Once the new process reports that its ready hooks succeeded, Listen drains
+// in-flight requests and returns; if the new process fails, it keeps serving.
 // Args:
 //
 //	addr (string): The address to listen on (e.g., ":8080").
+//	opts (...ListenOption): Options such as WithH2C or WithProxyProtocol.
 //
 // Returns:
 //
 //	error: An error if the server fails to start or shutdown.
-func (a *App) Listen(addr string) error {
+func (a *App) Listen(addr string, opts ...ListenOption) error {
+	if err := runHooks(context.Background(), a.hooks.start, true); err != nil {
+		return fmt.Errorf("start hook failed: %w", err)
+	}
+
+	ln, err := listen(addr)
+	if err != nil {
+		return fmt.Errorf("server error: %w", err)
+	}
+
 	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(stop, append([]os.Signal{os.Interrupt, syscall.SIGTERM}, restartSignals...)...)
 	defer signal.Stop(stop)
 
-	srv := &http.Server{
-		Addr:    addr,
-		Handler: a,
+	srv, serveLn, err := a.newServer(addr, ln, opts)
+	if err != nil {
+		ln.Close()
+		return fmt.Errorf("server error: %w", err)
 	}
 	serverError := make(chan error, 1)
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := srv.Serve(serveLn); err != nil && err != http.ErrServerClosed {
 			serverError <- err
 		}
 	}()
 
-	select {
-	case <-stop:
-		logger.LogInfo("Shutting down server...")
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+	if err := runHooks(context.Background(), a.hooks.ready, true); err != nil {
+		return errors.Join(fmt.Errorf("ready hook failed: %w", err), a.shutdown(srv, false))
+	}
+	if err := notifyReady(); err != nil {
+		logger.LogError("Could not notify the previous process: " + err.Error())
+	}
 
-		if err := srv.Shutdown(ctx); err != nil {
-			return fmt.Errorf("server shutdown failed: %w", err)
+	for {
+		select {
+		case sig := <-stop:
+			if isRestartSignal(sig) {
+				if err := restart(ln); err != nil {
+					logger.LogError("Graceful restart failed: " + err.Error())
+					continue
+				}
+				logger.LogInfo("Listener handed to new process, draining connections...")
+				return a.shutdown(srv, false)
+			}
+			return a.shutdown(srv, true)
+		case err := <-serverError:
+			return fmt.Errorf("server error: %w", err)
 		}
-		logger.LogInfo("Server gracefully stopped.")
-		return nil
-	case err := <-serverError:
-		return fmt.Errorf("server error: %w", err)
 	}
 }
+
+// shutdown stops srv, waiting for in-flight requests to finish. When drain is set
+// and health checks are enabled, readiness fails first and shutdown waits for the
+// drain delay. Shutdown hooks run next and stop hooks last, all within the
+// shutdown grace period.
+// Args:
+//
+//	srv (*http.Server): The server to stop.
+//	drain (bool): Whether to fail readiness before stopping.
+//
+// Returns:
+//
+//	error: An error if the server did not shut down cleanly.
+func (a *App) shutdown(srv *http.Server, drain bool) error {
+	logger.LogInfo("Shutting down server...")
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+
+	if drain && a.health != nil {
+		a.health.Drain()
+		select {
+		case <-time.After(a.health.DrainDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	var errs []error
+	if err := runHooks(ctx, a.hooks.shutdown, false); err != nil {
+		logger.LogError("Shutdown hook failed: " + err.Error())
+		errs = append(errs, fmt.Errorf("shutdown hook failed: %w", err))
+	}
+	if err := srv.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("server shutdown failed: %w", err))
+	}
+	if err := runHooks(ctx, a.hooks.stop, false); err != nil {
+		logger.LogError("Stop hook failed: " + err.Error())
+		errs = append(errs, fmt.Errorf("stop hook failed: %w", err))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	logger.LogInfo("Server gracefully stopped.")
+	return nil
+}
@@ -0,0 +1,145 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/BrunoCiccarino/GopherLight/req"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+func startH2CServer(t *testing.T) string {
+	t.Helper()
+	app := NewApp()
+	app.Get("/proto", func(r *req.Request, w *req.Response) {
+		w.Send(r.Req.Proto)
+	})
+	app.Get("/stream", func(r *req.Request, w *req.Response) {
+		for _, chunk := range []string{"one,", "two,", "three"} {
+			w.Write([]byte(chunk))
+			w.Flush()
+		}
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv, serveLn, err := app.newServer("", ln, []ListenOption{WithH2C()})
+	if err != nil {
+		t.Fatalf("Failed to configure server: %v", err)
+	}
+	go srv.Serve(serveLn)
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+	return ln.Addr().String()
+}
+
+func TestH2CPriorKnowledge(t *testing.T) {
+	addr := startH2CServer(t)
+
+	transport := &http.Transport{Protocols: new(http.Protocols)}
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: transport, Timeout: 5 * time.Second}
+
+	resp, err := client.Get("http://" + addr + "/stream")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("Expected HTTP/2 response, got %s", resp.Proto)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "one,two,three" {
+		t.Fatalf("Expected streamed body 'one,two,three', got '%s'", body)
+	}
+}
+
+func TestH2CStillServesHTTP1(t *testing.T) {
+	addr := startH2CServer(t)
+
+	resp, err := http.Get("http://" + addr + "/proto")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "HTTP/1.1" {
+		t.Fatalf("Expected HTTP/1.1 request, got '%s'", body)
+	}
+}
+
+func TestH2CUpgrade(t *testing.T) {
+	addr := startH2CServer(t)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	io.WriteString(conn, "GET /stream HTTP/1.1\r\n"+
+		"Host: example.com\r\n"+
+		"Connection: Upgrade, HTTP2-Settings\r\n"+
+		"Upgrade: h2c\r\n"+
+		"HTTP2-Settings: AAMAAABkAARAAAAA\r\n\r\n")
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101 Switching Protocols, got %d", resp.StatusCode)
+	}
+
+	// The upgraded request is answered on HTTP/2 stream 1.
+	io.WriteString(conn, http2.ClientPreface)
+	framer := http2.NewFramer(conn, br)
+	framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	if err := framer.WriteSettings(); err != nil {
+		t.Fatalf("Failed to send settings: %v", err)
+	}
+
+	var status string
+	var body bytes.Buffer
+	for ended := false; !ended; {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatalf("Failed to read frame: %v", err)
+		}
+		switch f := frame.(type) {
+		case *http2.SettingsFrame:
+			if !f.IsAck() {
+				framer.WriteSettingsAck()
+			}
+		case *http2.MetaHeadersFrame:
+			if f.StreamID == 1 {
+				status = f.PseudoValue("status")
+				ended = f.StreamEnded()
+			}
+		case *http2.DataFrame:
+			if f.StreamID == 1 {
+				body.Write(f.Data())
+				ended = f.StreamEnded()
+			}
+		}
+	}
+
+	if status != "200" {
+		t.Fatalf("Expected status 200 over HTTP/2, got '%s'", status)
+	}
+	if body.String() != "one,two,three" {
+		t.Fatalf("Expected streamed body 'one,two,three', got '%s'", body.String())
+	}
+}
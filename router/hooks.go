@@ -0,0 +1,79 @@
+package router
+
+import (
+	"context"
+	"errors"
+
+	"github.com/BrunoCiccarino/GopherLight/plugins"
+)
+
+// Hook is a function run at a stage of the application lifecycle.
+type Hook = plugins.Hook
+
+// lifecycle holds the hooks registered for each stage, in registration order.
+type lifecycle struct {
+	start    []Hook
+	ready    []Hook
+	shutdown []Hook
+	stop     []Hook
+}
+
+// OnStart registers a hook that runs before the server starts listening.
+// If any start hook fails, Listen returns its error without serving.
+// Args:
+//
+//	hook (Hook): The hook to run.
+func (a *App) OnStart(hook Hook) {
+	a.hooks.start = append(a.hooks.start, hook)
+}
+
+// OnReady registers a hook that runs once the listener is bound.
+// If any ready hook fails, the server is shut down and Listen returns its error.
+// Args:
+//
+//	hook (Hook): The hook to run.
+func (a *App) OnReady(hook Hook) {
+	a.hooks.ready = append(a.hooks.ready, hook)
+}
+
+// OnShutdown registers a hook that runs when shutdown starts, before
+// in-flight requests are drained. It shares the shutdown grace period.
+// Args:
+//
+//	hook (Hook): The hook to run.
+func (a *App) OnShutdown(hook Hook) {
+	a.hooks.shutdown = append(a.hooks.shutdown, hook)
+}
+
+// OnStop registers a hook that runs after the server has stopped serving.
+// It shares the shutdown grace period.
+// Args:
+//
+//	hook (Hook): The hook to run.
+func (a *App) OnStop(hook Hook) {
+	a.hooks.stop = append(a.hooks.stop, hook)
+}
+
+// runHooks runs hooks in order. When failFast is set it stops at the first
+// error; otherwise every hook runs and the errors are joined.
+// Args:
+//
+//	ctx (context.Context): The context passed to every hook.
+//	hooks ([]Hook): The hooks to run.
+//	failFast (bool): Whether to stop at the first error.
+//
+// Returns:
+//
+//	error: The first error, or all errors joined, or nil.
+func runHooks(ctx context.Context, hooks []Hook, failFast bool) error {
+	var errs []error
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			if failFast {
+				return err
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
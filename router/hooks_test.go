@@ -0,0 +1,134 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BrunoCiccarino/GopherLight/plugins"
+	"github.com/BrunoCiccarino/GopherLight/req"
+)
+
+func TestStartHookFailureAbortsListen(t *testing.T) {
+	app := NewApp()
+	startErr := errors.New("database unavailable")
+	readyCalled := false
+
+	app.OnStart(func(ctx context.Context) error { return startErr })
+	app.OnReady(func(ctx context.Context) error {
+		readyCalled = true
+		return nil
+	})
+
+	err := app.Listen("127.0.0.1:0")
+	if !errors.Is(err, startErr) {
+		t.Fatalf("Expected start hook error, got %v", err)
+	}
+	if readyCalled {
+		t.Fatal("Expected ready hooks not to run after a failed start hook")
+	}
+}
+
+func TestLifecycleHookOrder(t *testing.T) {
+	app := NewApp()
+	var calls []string
+	record := func(name string) Hook {
+		return func(ctx context.Context) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+
+	app.OnStart(record("start"))
+	app.OnReady(record("ready"))
+	app.OnReady(func(ctx context.Context) error {
+		p, err := os.FindProcess(os.Getpid())
+		if err != nil {
+			return err
+		}
+		return p.Signal(os.Interrupt)
+	})
+	app.OnShutdown(func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("Expected shutdown hook context to carry the grace period deadline")
+		}
+		calls = append(calls, "shutdown")
+		return nil
+	})
+	app.OnStop(record("stop"))
+
+	if err := app.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen returned error: %v", err)
+	}
+
+	expected := []string{"start", "ready", "shutdown", "stop"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Fatalf("Expected hook order %v, got %v", expected, calls)
+	}
+}
+
+type hookPlugin struct {
+	started bool
+}
+
+func (p *hookPlugin) Register(route func(method, path string, handler req.Handler)) {}
+
+func (p *hookPlugin) RegisterHooks(lc plugins.Lifecycle) {
+	lc.OnStart(func(ctx context.Context) error {
+		p.started = true
+		return errors.New("stop here")
+	})
+}
+
+func TestPluginRegistersHooks(t *testing.T) {
+	app := NewApp()
+	plugin := &hookPlugin{}
+	app.AddPlugin(plugin)
+	app.RegisterPlugins()
+
+	if err := app.Listen("127.0.0.1:0"); err == nil {
+		t.Fatal("Expected Listen to fail from the plugin's start hook")
+	}
+	if !plugin.started {
+		t.Fatal("Expected plugin start hook to run")
+	}
+}
+
+func TestReadinessFailsDuringShutdown(t *testing.T) {
+	app := NewApp()
+	app.EnableHealthChecks().DrainDelay = 10 * time.Millisecond
+
+	status := 0
+	var signalled time.Time
+	var drained time.Duration
+	app.OnReady(func(ctx context.Context) error {
+		signalled = time.Now()
+		p, err := os.FindProcess(os.Getpid())
+		if err != nil {
+			return err
+		}
+		return p.Signal(os.Interrupt)
+	})
+	app.OnShutdown(func(ctx context.Context) error {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+		status = w.Code
+		drained = time.Since(signalled)
+		return nil
+	})
+
+	if err := app.Listen("127.0.0.1:0"); err != nil {
+		t.Fatalf("Listen returned error: %v", err)
+	}
+	if status != http.StatusServiceUnavailable {
+		t.Fatalf("Expected readiness status %d during shutdown, got %d", http.StatusServiceUnavailable, status)
+	}
+	if drained < 10*time.Millisecond {
+		t.Fatalf("Expected shutdown to wait for the drain delay, waited %v", drained)
+	}
+}
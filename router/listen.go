@@ -0,0 +1,128 @@
+package router
+
+import (
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/BrunoCiccarino/GopherLight/proxyproto"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// ListenOption configures how App.Listen accepts connections.
+type ListenOption func(*listenConfig)
+
+type listenConfig struct {
+	h2c           bool
+	proxyProtocol *proxyproto.Config
+	wrappers      []func(net.Listener) net.Listener
+}
+
+// WithH2C makes Listen accept HTTP/2 over cleartext TCP, both from clients with
+// prior knowledge and from HTTP/1.1 clients that send "Upgrade: h2c". Other
+// HTTP/1.1 clients keep working.
+// Returns:
+//
+//	ListenOption: The option to pass to Listen.
+func WithH2C() ListenOption {
+	return func(c *listenConfig) {
+		c.h2c = true
+	}
+}
+
+// WithProxyProtocol makes Listen decode HAProxy PROXY protocol v1 and v2 headers
+// sent by the given trusted networks, so r.RemoteAddr reflects the real client.
+// Peers outside trustedCIDRs that send a header are rejected.
+// Args:
+//
+//	trustedCIDRs (...string): The networks of the load balancers, e.g. "10.0.0.0/8".
+//
+// Returns:
+//
+//	ListenOption: The option to pass to Listen.
+func WithProxyProtocol(trustedCIDRs ...string) ListenOption {
+	return func(c *listenConfig) {
+		c.proxyProtocol = &proxyproto.Config{TrustedCIDRs: trustedCIDRs}
+	}
+}
+
+// WithListener wraps the listening socket before the server accepts from it.
+// Wrappers run in order, after PROXY protocol decoding.
+// Args:
+//
+//	wrap (func(net.Listener) net.Listener): Returns the listener to serve on.
+//
+// Returns:
+//
+//	ListenOption: The option to pass to Listen.
+func WithListener(wrap func(net.Listener) net.Listener) ListenOption {
+	return func(c *listenConfig) {
+		c.wrappers = append(c.wrappers, wrap)
+	}
+}
+
+// newServer builds the http.Server and the listener it should serve on from
+// the raw listening socket and the listen options.
+// Args:
+//
+//	addr (string): The address passed to Listen.
+//	ln (net.Listener): The raw listening socket.
+//	opts ([]ListenOption): The listen options.
+//
+// Returns:
+//
+//	*http.Server: The configured server.
+//	net.Listener: The listener to pass to Serve.
+//	error: An error if an option is invalid.
+func (a *App) newServer(addr string, ln net.Listener, opts []ListenOption) (*http.Server, net.Listener, error) {
+	var cfg listenConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.proxyProtocol != nil {
+		pln, err := proxyproto.NewListener(ln, *cfg.proxyProtocol)
+		if err != nil {
+			return nil, nil, err
+		}
+		ln = pln
+	}
+	for _, wrap := range cfg.wrappers {
+		ln = wrap(ln)
+	}
+
+	// Each server has its own shutdown signal, so stopping one server does
+	// not end the event streams of another or of a later Listen.
+	shutdown := make(chan struct{})
+	var shutdownOnce sync.Once
+	srv := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			a.serve(w, r, shutdown)
+		}),
+	}
+	// http.Server.Shutdown does not wait for long-lived responses such as
+	// event streams, so tell them to end.
+	srv.RegisterOnShutdown(func() {
+		shutdownOnce.Do(func() { close(shutdown) })
+	})
+
+	if cfg.h2c {
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetUnencryptedHTTP2(true)
+
+		// Prior-knowledge connections reach h2s through the server's
+		// unencrypted HTTP/2 support; "Upgrade: h2c" requests are switched
+		// over by the h2c handler. ConfigureServer lets Shutdown send both
+		// kinds a GOAWAY.
+		h2s := &http2.Server{}
+		if err := http2.ConfigureServer(srv, h2s); err != nil {
+			return nil, nil, err
+		}
+		srv.Handler = h2c.NewHandler(srv.Handler, h2s)
+	}
+
+	return srv, ln, nil
+}
@@ -0,0 +1,187 @@
+package router
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/BrunoCiccarino/GopherLight/req"
+	"github.com/BrunoCiccarino/GopherLight/ws"
+)
+
+func TestListenWithProxyProtocol(t *testing.T) {
+	app := NewApp()
+	app.Get("/ip", func(r *req.Request, w *req.Response) {
+		w.Send(r.Req.RemoteAddr)
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv, serveLn, err := app.newServer("", ln, []ListenOption{WithProxyProtocol("127.0.0.0/8")})
+	if err != nil {
+		t.Fatalf("Failed to configure server: %v", err)
+	}
+	go srv.Serve(serveLn)
+	defer srv.Shutdown(context.Background())
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "PROXY TCP4 203.0.113.7 10.0.0.1 51234 80\r\nGET /ip HTTP/1.1\r\nHost: example.com\r\n\r\n")
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "203.0.113.7:51234" {
+		t.Fatalf("Expected RemoteAddr from PROXY header, got '%s'", body)
+	}
+}
+
+func TestListenWithInvalidProxyCIDR(t *testing.T) {
+	app := NewApp()
+	if err := app.Listen("127.0.0.1:0", WithProxyProtocol("bogus")); err == nil {
+		t.Fatal("Expected Listen to reject an invalid trusted CIDR")
+	}
+}
+
+func TestShutdownEndsEventStreams(t *testing.T) {
+	app := NewApp()
+	app.Get("/events", func(r *req.Request, w *req.Response) {
+		stream := w.SSE()
+		defer stream.Close()
+		stream.Send("hello", "1", "world")
+		<-stream.Done()
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	srv, serveLn, _ := app.newServer("", ln, nil)
+	go srv.Serve(serveLn)
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/events")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+	if line != "event: hello\n" {
+		t.Fatalf("Expected the first event, got %q", line)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Expected shutdown to end the stream, got %v", err)
+	}
+}
+
+func TestShutdownOnlyEndsItsOwnServerStreams(t *testing.T) {
+	app := NewApp()
+	ended := make(chan bool, 1)
+	app.Get("/events", func(r *req.Request, w *req.Response) {
+		stream := w.SSE()
+		select {
+		case <-stream.Done():
+			ended <- true
+		case <-time.After(100 * time.Millisecond):
+			ended <- false
+		}
+	})
+
+	start := func() (*http.Server, string) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		srv, serveLn, _ := app.newServer("", ln, nil)
+		go srv.Serve(serveLn)
+		return srv, ln.Addr().String()
+	}
+	first, _ := start()
+	second, addr := start()
+	defer second.Shutdown(context.Background())
+
+	if err := first.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Failed to shut down: %v", err)
+	}
+
+	resp, err := http.Get("http://" + addr + "/events")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if <-ended {
+		t.Fatal("Expected the other server's stream to stay open")
+	}
+}
+
+func TestRouteClosesEventStreamWhenHandlerReturns(t *testing.T) {
+	app := NewApp()
+	var stream *req.EventStream
+	app.Get("/events", func(r *req.Request, w *req.Response) {
+		stream = w.SSE()
+		stream.KeepAlive(time.Millisecond)
+	})
+
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/events", nil))
+
+	select {
+	case <-stream.Done():
+	default:
+		t.Fatal("Expected the stream to be closed when the handler returned")
+	}
+}
+
+func TestAppWebSocket(t *testing.T) {
+	app := NewApp()
+	app.WebSocket("/ws", func(conn *ws.Conn) {
+		messageType, data, err := conn.ReadMessage()
+		if err == nil {
+			conn.WriteMessage(messageType, append([]byte("echo: "), data...))
+		}
+	})
+
+	srv := httptest.NewServer(app)
+	defer srv.Close()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: "+srv.Listener.Addr().String()+"\r\n"+
+		"Connection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Key: "+key+"\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != ws.AcceptKey(key) {
+		t.Fatalf("Unexpected handshake response: %d %v", resp.StatusCode, resp.Header)
+	}
+
+	// A masked text frame with the payload "hi" and a zero masking key.
+	conn.Write([]byte{0x81, 0x82, 0, 0, 0, 0, 'h', 'i'})
+	reply := make([]byte, 10)
+	if _, err := io.ReadFull(br, reply); err != nil {
+		t.Fatalf("Failed to read reply frame: %v", err)
+	}
+	if string(reply[2:]) != "echo: hi" {
+		t.Fatalf("Expected echoed message, got %q", reply[2:])
+	}
+}
@@ -0,0 +1,112 @@
+package router
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listenerFDEnv is the environment variable used to tell a restarted process
+// which inherited file descriptor holds the listening socket.
+const listenerFDEnv = "GOPHERLIGHT_LISTENER_FD"
+
+// readyFDEnv is the environment variable used to tell a restarted process
+// which inherited file descriptor to write to once it is ready to serve.
+const readyFDEnv = "GOPHERLIGHT_READY_FD"
+
+// restartTimeout is how long the old process waits for the new one to become
+// ready before it gives up on the restart and keeps serving.
+var restartTimeout = 30 * time.Second
+
+// listen returns the listener handed over by a parent process during a graceful
+// restart, or binds a new TCP listener on addr when there is none.
+// Args:
+//
+//	addr (string): The address to listen on (e.g., ":8080").
+//
+// Returns:
+//
+//	net.Listener: The listener the server should accept connections on.
+//	error: An error if the listener could not be inherited or bound.
+func listen(addr string) (net.Listener, error) {
+	ln, err := inheritedListener()
+	if err != nil || ln != nil {
+		return ln, err
+	}
+	if addr == "" {
+		addr = ":http"
+	}
+	return net.Listen("tcp", addr)
+}
+
+// inheritedListener rebuilds the listener passed by a parent process through
+// the listenerFDEnv environment variable. It returns a nil listener when the
+// process was not started by a graceful restart.
+func inheritedListener() (net.Listener, error) {
+	value := os.Getenv(listenerFDEnv)
+	if value == "" {
+		return nil, nil
+	}
+	os.Unsetenv(listenerFDEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q: %w", listenerFDEnv, value, err)
+	}
+
+	f := os.NewFile(uintptr(fd), "listener")
+	if f == nil {
+		return nil, fmt.Errorf("invalid inherited listener descriptor %d", fd)
+	}
+	defer f.Close()
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("inherit listener: %w", err)
+	}
+	return ln, nil
+}
+
+// notifyReady tells the parent process of a graceful restart that this
+// process has started and its ready hooks succeeded, so the parent can stop
+// serving. It does nothing when the process was not started by a restart.
+// Returns:
+//
+//	error: An error if the parent could not be notified.
+func notifyReady() error {
+	value := os.Getenv(readyFDEnv)
+	if value == "" {
+		return nil
+	}
+	os.Unsetenv(readyFDEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s value %q: %w", readyFDEnv, value, err)
+	}
+
+	f := os.NewFile(uintptr(fd), "ready")
+	if f == nil {
+		return fmt.Errorf("invalid inherited ready descriptor %d", fd)
+	}
+	defer f.Close()
+
+	_, err = f.Write([]byte{1})
+	return err
+}
+
+// restartEnv returns the current environment with the listener and ready
+// descriptor variables pointing at listenerFD and readyFD, replacing any
+// previous values.
+func restartEnv(listenerFD, readyFD int) []string {
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, listenerFDEnv+"=") && !strings.HasPrefix(kv, readyFDEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	return append(env, listenerFDEnv+"="+strconv.Itoa(listenerFD), readyFDEnv+"="+strconv.Itoa(readyFD))
+}
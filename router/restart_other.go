@@ -0,0 +1,20 @@
+//go:build !unix
+
+package router
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// restartSignals is empty on platforms without SIGUSR2.
+var restartSignals []os.Signal
+
+func isRestartSignal(sig os.Signal) bool {
+	return false
+}
+
+func restart(ln net.Listener) error {
+	return errors.New("graceful restart is not supported on this platform")
+}
@@ -0,0 +1,171 @@
+//go:build unix
+
+package router
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// restartChildEnv tells a copy of the test binary started by restart how to
+// behave as the new process.
+const restartChildEnv = "GOPHERLIGHT_TEST_RESTART_CHILD"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(restartChildEnv) {
+	case "":
+		os.Exit(m.Run())
+	case "fail":
+		app := NewApp()
+		app.OnStart(func(ctx context.Context) error { return errors.New("migration failed") })
+		app.Listen("127.0.0.1:0")
+		os.Exit(1)
+	case "ready":
+		app := NewApp()
+		app.OnReady(func(ctx context.Context) error {
+			go func() {
+				time.Sleep(time.Second)
+				os.Exit(0)
+			}()
+			return nil
+		})
+		app.Listen("127.0.0.1:0")
+		os.Exit(1)
+	}
+}
+
+func TestInheritedListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("Failed to duplicate listener: %v", err)
+	}
+	defer f.Close()
+
+	t.Setenv(listenerFDEnv, strconv.Itoa(int(f.Fd())))
+
+	inherited, err := listen(":0")
+	if err != nil {
+		t.Fatalf("Failed to inherit listener: %v", err)
+	}
+	defer inherited.Close()
+
+	if inherited.Addr().String() != ln.Addr().String() {
+		t.Fatalf("Expected inherited address %s, got %s", ln.Addr(), inherited.Addr())
+	}
+	if os.Getenv(listenerFDEnv) != "" {
+		t.Fatalf("Expected %s to be cleared after inheriting", listenerFDEnv)
+	}
+}
+
+func TestRestartWaitsForReadyChild(t *testing.T) {
+	t.Setenv(restartChildEnv, "ready")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	if err := restart(ln); err != nil {
+		t.Fatalf("Expected restart to succeed, got %v", err)
+	}
+}
+
+func TestRestartKeepsServingWhenChildFails(t *testing.T) {
+	t.Setenv(restartChildEnv, "fail")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	if err := restart(ln); err == nil {
+		t.Fatal("Expected restart to fail when the new process fails to start")
+	}
+
+	accepted := make(chan error, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			conn.Close()
+		}
+		accepted <- err
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Expected the listener to stay open, got %v", err)
+	}
+	conn.Close()
+	if err := <-accepted; err != nil {
+		t.Fatalf("Expected the old process to keep accepting, got %v", err)
+	}
+}
+
+func TestRestartTimesOutOnSilentChild(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	if err := waitReady(r, 50*time.Millisecond); err == nil {
+		t.Fatal("Expected waitReady to time out")
+	}
+}
+
+func TestNotifyReady(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	fd, err := syscall.Dup(int(w.Fd()))
+	w.Close()
+	if err != nil {
+		t.Fatalf("Failed to duplicate pipe: %v", err)
+	}
+
+	t.Setenv(readyFDEnv, strconv.Itoa(fd))
+	if err := notifyReady(); err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+	if err := waitReady(r, time.Second); err != nil {
+		t.Fatalf("Expected the ready report, got %v", err)
+	}
+	if os.Getenv(readyFDEnv) != "" {
+		t.Fatalf("Expected %s to be cleared after notifying", readyFDEnv)
+	}
+}
+
+func TestRestartEnv(t *testing.T) {
+	t.Setenv(listenerFDEnv, "7")
+	t.Setenv(readyFDEnv, "8")
+
+	count := 0
+	for _, kv := range restartEnv(3, 4) {
+		if kv == readyFDEnv+"=8" {
+			t.Fatalf("Expected stale %s value to be removed", readyFDEnv)
+		}
+		if kv == listenerFDEnv+"=7" {
+			t.Fatalf("Expected stale %s value to be removed", listenerFDEnv)
+		}
+		if kv == listenerFDEnv+"=3" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Fatalf("Expected exactly one %s=3 entry, got %d", listenerFDEnv, count)
+	}
+}
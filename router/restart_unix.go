@@ -0,0 +1,106 @@
+//go:build unix
+
+package router
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// restartSignals lists the signals that trigger a zero-downtime restart.
+var restartSignals = []os.Signal{syscall.SIGUSR2}
+
+func isRestartSignal(sig os.Signal) bool {
+	return sig == syscall.SIGUSR2
+}
+
+// restart starts a new copy of the running binary and hands it the listening
+// socket. The new process finds the socket through listenerFDEnv and reports
+// that it is ready through a pipe named by readyFDEnv. restart returns once
+// that report arrives; if the new process exits or does not become ready
+// within restartTimeout, it is killed and an error is returned so the current
+// process keeps serving.
+// Args:
+//
+//	ln (net.Listener): The listener to hand over.
+//
+// Returns:
+//
+//	error: An error if the listener cannot be shared or the new process does not become ready.
+func restart(ln net.Listener) error {
+	fl, ok := ln.(interface{ File() (*os.File, error) })
+	if !ok {
+		return errors.New("listener does not support file descriptor handoff")
+	}
+	f, err := fl.File()
+	if err != nil {
+		return fmt.Errorf("duplicate listener: %w", err)
+	}
+	defer f.Close()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate executable: %w", err)
+	}
+
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("create ready pipe: %w", err)
+	}
+	defer ready.Close()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// ExtraFiles start right after stdin, stdout and stderr.
+	cmd.ExtraFiles = []*os.File{f, readyW}
+	cmd.Env = restartEnv(3, 4)
+
+	err = cmd.Start()
+	// Only the new process may hold the write end, so the read below ends
+	// with io.EOF if it exits without reporting.
+	readyW.Close()
+	if err != nil {
+		return fmt.Errorf("start new process: %w", err)
+	}
+
+	if err := waitReady(ready, restartTimeout); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// waitReady waits for the new process to write to the ready pipe.
+// Args:
+//
+//	ready (*os.File): The read end of the ready pipe.
+//	timeout (time.Duration): How long to wait.
+//
+// Returns:
+//
+//	error: An error if the process closed the pipe or the timeout expired first.
+func waitReady(ready *os.File, timeout time.Duration) error {
+	if err := ready.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return fmt.Errorf("wait for new process: %w", err)
+	}
+	_, err := ready.Read(make([]byte, 1))
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, io.EOF):
+		return errors.New("new process exited before it was ready")
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return fmt.Errorf("new process was not ready after %s", timeout)
+	default:
+		return fmt.Errorf("wait for new process: %w", err)
+	}
+}
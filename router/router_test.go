@@ -1,12 +1,16 @@
 package router
 
 import (
+	"bytes"
+	"compress/gzip"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/BrunoCiccarino/GopherLight/req"
+	"github.com/BrunoCiccarino/GopherLight/view"
 )
 
 func TestAppRouteGET(t *testing.T) {
@@ -130,6 +134,12 @@ func TestAppRouteNotFound(t *testing.T) {
 	if w.Code != http.StatusNotFound {
 		t.Fatalf("Expected status %d, got %d", http.StatusNotFound, w.Code)
 	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Expected problem+json, got %s", ct)
+	}
+	if body := w.Body.String(); !strings.Contains(body, `"status":404`) || !strings.Contains(body, `"instance":"/unknown"`) {
+		t.Fatalf("Unexpected problem body: %s", body)
+	}
 }
 
 func TestAppRouteMethodNotAllowed(t *testing.T) {
@@ -147,6 +157,9 @@ func TestAppRouteMethodNotAllowed(t *testing.T) {
 	if w.Code != http.StatusMethodNotAllowed {
 		t.Fatalf("Expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
 	}
+	if w.Header().Get("Allow") != "GET" || w.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("Expected Allow and problem+json headers, got %v", w.Header())
+	}
 }
 
 func TestAppRouteNested(t *testing.T) {
@@ -170,3 +183,226 @@ func TestAppRouteNested(t *testing.T) {
 		t.Fatalf("Expected body '%s', got '%s'", expectedBody, w.Body.String())
 	}
 }
+
+func TestAppRouteBodyTooLarge(t *testing.T) {
+	app := NewApp()
+	app.SetMaxBodySize(4)
+
+	app.Post("/upload", func(req *req.Request, res *req.Response) {
+		body, err := req.BodyBytes()
+		if err != nil {
+			return
+		}
+		res.Send(string(body))
+	})
+
+	req := httptest.NewRequest("POST", "/upload", strings.NewReader("too large"))
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/upload", strings.NewReader("too large"))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected status %d for a body without Content-Length, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
+
+func TestAppRoutePathParams(t *testing.T) {
+	app := NewApp()
+
+	app.Get("/users/:id", func(req *req.Request, res *req.Response) {
+		res.Send("user " + req.Param("id"))
+	})
+	app.Get("/users/me", func(req *req.Request, res *req.Response) {
+		res.Send("me")
+	})
+
+	for path, expected := range map[string]string{"/users/42": "user 42", "/users/me": "me"} {
+		req := httptest.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+
+		if w.Body.String() != expected {
+			t.Fatalf("Expected body '%s' for %s, got '%s'", expected, path, w.Body.String())
+		}
+	}
+}
+
+func TestAppRouteConflictingParams(t *testing.T) {
+	app := NewApp()
+	app.Get("/users/:id", func(req *req.Request, res *req.Response) {})
+	app.Post("/users/:name", func(req *req.Request, res *req.Response) {})
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected a panic for conflicting parameter names")
+		}
+	}()
+	app.Get("/users/:name/posts", func(req *req.Request, res *req.Response) {})
+}
+
+func TestAppRouteBindErrorAnswers400(t *testing.T) {
+	app := NewApp()
+
+	app.Post("/users", func(r *req.Request, res *req.Response) {
+		var input struct {
+			Age int `json:"age"`
+		}
+		if err := r.Bind(&input); err != nil {
+			return
+		}
+		res.Send("created")
+	})
+
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(`{"age":`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `"fields"`) {
+		t.Fatalf("Expected field errors in body, got '%s'", w.Body.String())
+	}
+}
+
+func TestAppRouteClientIP(t *testing.T) {
+	app := NewApp()
+	if err := app.SetTrustedProxies("10.0.0.0/8"); err != nil {
+		t.Fatalf("SetTrustedProxies returned error: %v", err)
+	}
+
+	app.Get("/ip", func(r *req.Request, res *req.Response) {
+		res.Send(r.ClientIP())
+	})
+
+	req := httptest.NewRequest("GET", "/ip", nil)
+	req.RemoteAddr = "10.1.2.3:4567"
+	req.Header.Set("X-Forwarded-For", "203.0.113.5")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	if w.Body.String() != "203.0.113.5" {
+		t.Fatalf("Expected client IP '203.0.113.5', got '%s'", w.Body.String())
+	}
+}
+
+func TestAppLocalsFromMiddleware(t *testing.T) {
+	app := NewApp()
+	app.Use(func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			req.Set(r, "tenant", "acme")
+			next(w, r)
+		}
+	})
+
+	app.Get("/tenant", func(r *req.Request, res *req.Response) {
+		tenant, _ := req.Get[string](r, "tenant")
+		res.Send(tenant)
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/tenant", nil))
+
+	if w.Body.String() != "acme" {
+		t.Fatalf("Expected tenant 'acme' from middleware, got '%s'", w.Body.String())
+	}
+}
+
+func TestAppRouteCompressedBody(t *testing.T) {
+	app := NewApp()
+	app.Post("/ingest", func(r *req.Request, res *req.Response) {
+		var input struct {
+			Name string `json:"name"`
+		}
+		if err := r.Bind(&input); err != nil {
+			return
+		}
+		res.Send(input.Name)
+	})
+
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	gz.Write([]byte(`{"name":"Gopher"}`))
+	gz.Close()
+
+	req := httptest.NewRequest("POST", "/ingest", &body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != "Gopher" {
+		t.Fatalf("Expected decompressed body to be bound, got %d '%s'", w.Code, w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/ingest", strings.NewReader("x"))
+	req.Header.Set("Content-Encoding", "br")
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnsupportedMediaType || w.Header().Get("Accept-Encoding") != "gzip, deflate" {
+		t.Fatalf("Expected 415 with Accept-Encoding, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestAppErrorHandler(t *testing.T) {
+	app := NewApp()
+	var handled []string
+	app.SetErrorHandler(func(err error, r *req.Request, w *req.Response) {
+		handled = append(handled, err.Error())
+		w.Status(req.StatusCode(err)).JSON(map[string]string{"message": err.Error()})
+	})
+	app.Get("/old", func(r *req.Request, res *req.Response) {
+		res.Send("old style")
+	})
+	app.Get("/new", req.HandleE(func(r *req.Request, res *req.Response) error {
+		return req.NewHTTPError(http.StatusForbidden, "not yours")
+	}))
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/old", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "old style" {
+		t.Fatalf("Expected plain handler to keep working, got %d '%s'", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/new", nil))
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), `"message":"not yours"`) {
+		t.Fatalf("Expected custom error response, got %d '%s'", w.Code, w.Body.String())
+	}
+	if len(handled) != 1 || handled[0] != "not yours" {
+		t.Fatalf("Expected the error handler to be called once, got %v", handled)
+	}
+}
+
+func TestAppRenderViews(t *testing.T) {
+	engine, err := view.New(fstest.MapFS{
+		"layouts/main.html": {Data: []byte(`<body>{{template "content" .}}</body>`)},
+		"users/show.html":   {Data: []byte(`<h1>{{.Name}}</h1>`)},
+	}, view.WithLayout("layouts/main"))
+	if err != nil {
+		t.Fatalf("view.New returned error: %v", err)
+	}
+
+	app := NewApp()
+	app.SetViews(engine)
+	app.Get("/users/:name", func(r *req.Request, res *req.Response) {
+		res.Render("users/show", map[string]string{"Name": r.Param("name")})
+	})
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest("GET", "/users/gopher", nil))
+
+	if w.Code != http.StatusOK || w.Body.String() != "<body><h1>gopher</h1></body>" {
+		t.Fatalf("Expected rendered page, got %d '%s'", w.Code, w.Body.String())
+	}
+}
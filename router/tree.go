@@ -2,12 +2,14 @@ package router
 
 import (
 	"net/http"
+	"strings"
 )
 
 type Node struct {
 	segment  string
 	handler  http.HandlerFunc
 	children map[string]*Node
+	param    *Node
 }
 
 func NewNode(segment string) *Node {
@@ -17,6 +19,10 @@ func NewNode(segment string) *Node {
 	}
 }
 
+// AddRoute adds handler at path. A segment starting with ":" (e.g. ":id")
+// matches any single segment and captures it as a path parameter. It panics
+// if a parameter at the same position was registered under another name,
+// since one of the names would never be set.
 func (n *Node) AddRoute(path []string, handler http.HandlerFunc) {
 
 	if len(path) == 0 {
@@ -25,6 +31,16 @@ func (n *Node) AddRoute(path []string, handler http.HandlerFunc) {
 	}
 
 	nextSegment := path[0]
+	if strings.HasPrefix(nextSegment, ":") && len(nextSegment) > 1 {
+		if n.param == nil {
+			n.param = NewNode(nextSegment)
+		} else if n.param.segment != nextSegment {
+			panic("router: parameter " + nextSegment + " conflicts with " + n.param.segment + " registered at the same position")
+		}
+		n.param.AddRoute(path[1:], handler)
+		return
+	}
+
 	child, exists := n.children[nextSegment]
 	if !exists {
 		child = NewNode(nextSegment)
@@ -35,15 +51,34 @@ func (n *Node) AddRoute(path []string, handler http.HandlerFunc) {
 }
 
 func (n *Node) FindRoute(path []string) (http.HandlerFunc, bool) {
+	handler, _, ok := n.Lookup(path)
+	return handler, ok
+}
+
+// Lookup finds the handler for path along with the path parameters captured
+// by ":name" segments. Static segments take precedence over parameters.
+func (n *Node) Lookup(path []string) (http.HandlerFunc, map[string]string, bool) {
 	if len(path) == 0 {
-		return n.handler, n.handler != nil
+		return n.handler, nil, n.handler != nil
 	}
 
 	nextSegment := path[0]
-	child, exists := n.children[nextSegment]
-	if !exists {
-		return nil, false
+	if child, exists := n.children[nextSegment]; exists {
+		if handler, params, ok := child.Lookup(path[1:]); ok {
+			return handler, params, true
+		}
 	}
 
-	return child.FindRoute(path[1:])
+	if n.param == nil || nextSegment == "" {
+		return nil, nil, false
+	}
+	handler, params, ok := n.param.Lookup(path[1:])
+	if !ok {
+		return nil, nil, false
+	}
+	if params == nil {
+		params = make(map[string]string)
+	}
+	params[n.param.segment[1:]] = nextSegment
+	return handler, params, true
 }
@@ -0,0 +1,220 @@
+// Package view renders HTML pages from html/template files with shared
+// layouts and partials, loaded from any fs.FS such as an embed.FS or
+// os.DirFS. In development the templates can be reloaded when they change.
+//
+// Files are named by their path without the extension. Everything under
+// layouts/ and partials/ is available to every page; any other file is a
+// page. A page is parsed as the "content" template, so a layout includes it
+// with {{template "content" .}}, and it may define further blocks the
+// layout declares, such as {{define "title"}}.
+package view
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound is returned for a page that does not exist.
+var ErrNotFound = errors.New("view: page not found")
+
+// Option configures an Engine.
+type Option func(*Engine)
+
+// WithLayout sets the layout pages are rendered in, such as "layouts/main".
+// Without it pages are rendered on their own.
+func WithLayout(name string) Option {
+	return func(e *Engine) {
+		e.layout = name
+	}
+}
+
+// WithFuncs adds functions templates can call. They must be added here,
+// before the templates are parsed.
+func WithFuncs(funcs template.FuncMap) Option {
+	return func(e *Engine) {
+		for name, fn := range funcs {
+			e.funcs[name] = fn
+		}
+	}
+}
+
+// WithExtension sets the extension of template files. The default is ".html".
+func WithExtension(ext string) Option {
+	return func(e *Engine) {
+		e.ext = ext
+	}
+}
+
+// WithReload makes the engine check the files before each render and parse
+// them again when any was added, removed or modified. Use it in development
+// with os.DirFS; it costs a directory walk per render.
+func WithReload(reload bool) Option {
+	return func(e *Engine) {
+		e.reload = reload
+	}
+}
+
+// Engine renders pages. It is safe for concurrent use.
+type Engine struct {
+	fsys   fs.FS
+	layout string
+	funcs  template.FuncMap
+	ext    string
+	reload bool
+
+	mu      sync.RWMutex
+	base    *template.Template
+	pages   map[string]*template.Template
+	version string
+}
+
+// New returns an Engine for the templates in fsys. Layouts and partials are
+// parsed right away so syntax errors surface at startup; pages are parsed
+// the first time they are rendered.
+func New(fsys fs.FS, opts ...Option) (*Engine, error) {
+	e := &Engine{fsys: fsys, funcs: template.FuncMap{}, ext: ".html"}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if err := e.load(); err != nil {
+		return nil, err
+	}
+	if e.layout != "" && e.base.Lookup(e.layout) == nil {
+		return nil, fmt.Errorf("view: layout %q not found", e.layout)
+	}
+	return e, nil
+}
+
+// Render executes the page name with data and writes the result to w,
+// inside the layout if one is set.
+func (e *Engine) Render(w io.Writer, name string, data interface{}) error {
+	return e.RenderLayout(w, name, e.layout, data)
+}
+
+// RenderLayout is Render with a different layout. An empty layout renders
+// the page on its own.
+func (e *Engine) RenderLayout(w io.Writer, name, layout string, data interface{}) error {
+	if e.reload {
+		if err := e.reloadIfChanged(); err != nil {
+			return err
+		}
+	}
+	t, err := e.page(name)
+	if err != nil {
+		return err
+	}
+	if layout == "" {
+		layout = "content"
+	}
+	return t.ExecuteTemplate(w, layout, data)
+}
+
+// load parses the layouts and partials and forgets the parsed pages.
+func (e *Engine) load() error {
+	base := template.New("").Funcs(e.funcs)
+	err := fs.WalkDir(e.fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !e.shared(path) {
+			return err
+		}
+		return e.parse(base, strings.TrimSuffix(path, e.ext), path)
+	})
+	if err != nil {
+		return err
+	}
+	version, err := e.fingerprint()
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.base, e.pages, e.version = base, map[string]*template.Template{}, version
+	return nil
+}
+
+func (e *Engine) page(name string) (*template.Template, error) {
+	e.mu.RLock()
+	t, ok := e.pages[name]
+	base := e.base
+	e.mu.RUnlock()
+	if ok {
+		return t, nil
+	}
+
+	path := name + e.ext
+	if e.shared(path) {
+		return nil, fmt.Errorf("%w: %s is a layout or partial", ErrNotFound, name)
+	}
+	t, err := base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if err := e.parse(t, "content", path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.base == base {
+		e.pages[name] = t
+	}
+	return t, nil
+}
+
+func (e *Engine) parse(t *template.Template, name, path string) error {
+	src, err := fs.ReadFile(e.fsys, path)
+	if err != nil {
+		return err
+	}
+	if _, err := t.New(name).Parse(string(src)); err != nil {
+		return fmt.Errorf("view: %s: %w", path, err)
+	}
+	return nil
+}
+
+// shared reports whether path is a layout or partial.
+func (e *Engine) shared(path string) bool {
+	return strings.HasSuffix(path, e.ext) &&
+		(strings.HasPrefix(path, "layouts/") || strings.HasPrefix(path, "partials/"))
+}
+
+func (e *Engine) reloadIfChanged() error {
+	version, err := e.fingerprint()
+	if err != nil {
+		return err
+	}
+	e.mu.RLock()
+	changed := version != e.version
+	e.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+	return e.load()
+}
+
+// fingerprint summarizes the name, size and modification time of every
+// template file, so any change to the set of files alters it.
+func (e *Engine) fingerprint() (string, error) {
+	var b bytes.Buffer
+	err := fs.WalkDir(e.fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, e.ext) {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return b.String(), err
+}
@@ -0,0 +1,122 @@
+package view
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+var testFiles = fstest.MapFS{
+	"layouts/main.html":  {Data: []byte(`<title>{{block "title" .}}Site{{end}}</title>{{template "partials/nav" .}}<main>{{template "content" .}}</main>`)},
+	"layouts/bare.html":  {Data: []byte(`[{{template "content" .}}]`)},
+	"partials/nav.html":  {Data: []byte(`<nav>{{.User | upper}}</nav>`)},
+	"users/show.html":    {Data: []byte(`{{define "title"}}{{.User}}'s profile{{end}}<p>{{.Bio}}</p>`)},
+	"home.html":          {Data: []byte(`<p>Welcome</p>`)},
+	"README.md":          {Data: []byte(`not a template`)},
+	"partials/empty.txt": {Data: []byte(`ignored`)},
+}
+
+func newTestEngine(t *testing.T) *Engine {
+	t.Helper()
+	e, err := New(testFiles,
+		WithLayout("layouts/main"),
+		WithFuncs(template.FuncMap{"upper": strings.ToUpper}))
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	return e
+}
+
+func TestRenderWithLayout(t *testing.T) {
+	e := newTestEngine(t)
+
+	var buf bytes.Buffer
+	data := map[string]string{"User": "gopher", "Bio": "<script>alert(1)</script>"}
+	if err := e.Render(&buf, "users/show", data); err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	want := `<title>gopher's profile</title><nav>GOPHER</nav><main><p>&lt;script&gt;alert(1)&lt;/script&gt;</p></main>`
+	if buf.String() != want {
+		t.Fatalf("Unexpected output:\n got %s\nwant %s", buf.String(), want)
+	}
+
+	buf.Reset()
+	e.Render(&buf, "home", map[string]string{"User": "gopher"})
+	if !strings.HasPrefix(buf.String(), "<title>Site</title>") {
+		t.Fatalf("Expected the layout's default title, got %s", buf.String())
+	}
+}
+
+func TestRenderLayout(t *testing.T) {
+	e := newTestEngine(t)
+
+	var buf bytes.Buffer
+	e.RenderLayout(&buf, "home", "layouts/bare", nil)
+	if buf.String() != "[<p>Welcome</p>]" {
+		t.Fatalf("Expected the bare layout, got %s", buf.String())
+	}
+
+	buf.Reset()
+	e.RenderLayout(&buf, "home", "", nil)
+	if buf.String() != "<p>Welcome</p>" {
+		t.Fatalf("Expected the page alone, got %s", buf.String())
+	}
+}
+
+func TestRenderMissingPage(t *testing.T) {
+	e := newTestEngine(t)
+
+	for _, name := range []string{"users/missing", "../secret", "partials/nav"} {
+		if err := e.Render(&bytes.Buffer{}, name, nil); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Render(%q): expected ErrNotFound, got %v", name, err)
+		}
+	}
+}
+
+func TestNewReportsErrors(t *testing.T) {
+	broken := fstest.MapFS{"layouts/main.html": {Data: []byte(`{{if}}`)}}
+	if _, err := New(broken); err == nil || !strings.Contains(err.Error(), "layouts/main.html") {
+		t.Fatalf("Expected a parse error naming the file, got %v", err)
+	}
+	if _, err := New(testFiles, WithLayout("layouts/missing")); err == nil {
+		t.Fatal("Expected an error for a missing layout")
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "home.html")
+	os.WriteFile(page, []byte("v1"), 0o644)
+
+	render := func(e *Engine) string {
+		var buf bytes.Buffer
+		if err := e.Render(&buf, "home", nil); err != nil {
+			t.Fatalf("Render returned error: %v", err)
+		}
+		return buf.String()
+	}
+	update := func(content string, age time.Duration) {
+		os.WriteFile(page, []byte(content), 0o644)
+		mtime := time.Now().Add(age)
+		os.Chtimes(page, mtime, mtime)
+	}
+
+	cached, _ := New(os.DirFS(dir))
+	live, _ := New(os.DirFS(dir), WithReload(true))
+	render(cached)
+	render(live)
+
+	update("v2", time.Minute)
+	if got := render(cached); got != "v1" {
+		t.Fatalf("Expected the cached page without reload, got %s", got)
+	}
+	if got := render(live); got != "v2" {
+		t.Fatalf("Expected the changed page with reload, got %s", got)
+	}
+}
@@ -0,0 +1,596 @@
+package ws
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// MessageType is the type of a data message.
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+// Frame opcodes.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// Close codes defined by RFC 6455 and the IANA registry.
+const (
+	CloseNormal             = 1000
+	CloseGoingAway          = 1001
+	CloseProtocolError      = 1002
+	CloseUnsupportedData    = 1003
+	CloseNoStatus           = 1005
+	CloseAbnormal           = 1006
+	CloseInvalidPayload     = 1007
+	ClosePolicyViolation    = 1008
+	CloseMessageTooBig      = 1009
+	CloseMandatoryExtension = 1010
+	CloseInternalError      = 1011
+	CloseServiceRestart     = 1012
+	CloseTryAgainLater      = 1013
+)
+
+// maxControlPayload is the largest payload of a ping, pong or close frame.
+const maxControlPayload = 125
+
+// compressThreshold is the smallest message worth compressing.
+const compressThreshold = 64
+
+// ErrClosed is returned when using a connection that has been closed.
+var ErrClosed = errors.New("ws: connection closed")
+
+// CloseError is returned by ReadMessage when the connection is closed. Code
+// is the status code the peer sent, CloseNoStatus if it sent none, or
+// CloseAbnormal if the connection dropped without a close frame. Protocol
+// violations by the peer are reported with the code the server closed with.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	s := "ws: closed with status " + strconv.Itoa(e.Code)
+	if e.Text != "" {
+		s += ": " + e.Text
+	}
+	return s
+}
+
+// Conn is a WebSocket connection. One goroutine may read while others
+// write; writes of whole messages and control frames are serialized.
+type Conn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	server      bool
+	cfg         config
+	request     *http.Request
+	subprotocol string
+	deflate     *deflateParams
+
+	readMu      sync.Mutex
+	readErr     error
+	readDict    []byte
+	pongHandler func(data string)
+
+	msgMu     sync.Mutex // held while a message is being written
+	writeMu   sync.Mutex // held while a frame is being written
+	writeErr  error
+	closeSent bool
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, server bool, cfg config) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	return &Conn{conn: conn, br: br, server: server, cfg: cfg, closed: make(chan struct{})}
+}
+
+// Request returns the opening handshake request.
+func (c *Conn) Request() *http.Request {
+	return c.request
+}
+
+// Subprotocol returns the negotiated subprotocol, or "".
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// Compressed reports whether permessage-deflate was negotiated.
+func (c *Conn) Compressed() bool {
+	return c.deflate != nil
+}
+
+// RemoteAddr returns the address of the peer.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadLimit sets the largest message ReadMessage accepts, in bytes after
+// decompression. Zero or less removes the limit.
+func (c *Conn) SetReadLimit(limit int64) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	c.cfg.readLimit = limit
+}
+
+// SetReadDeadline sets the deadline for reading the next message.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for writes.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetPongHandler sets the function called with the payload of each pong
+// received while reading. Pings are answered automatically.
+func (c *Conn) SetPongHandler(handler func(data string)) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	c.pongHandler = handler
+}
+
+// ReadMessage reads the next data message, answering pings and handling
+// close frames while it waits. When the peer closes the connection or
+// breaks the protocol, it returns a *CloseError and the connection is
+// closed; later calls return the same error.
+func (c *Conn) ReadMessage() (MessageType, []byte, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	messageType, data, err := c.readMessage()
+	if err != nil {
+		c.readErr = err
+	}
+	return messageType, data, err
+}
+
+func (c *Conn) readMessage() (MessageType, []byte, error) {
+	var (
+		started    bool
+		messageOp  byte
+		compressed bool
+		payload    []byte
+	)
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch f.op {
+		case opPing:
+			if err := c.writeControl(opPong, f.payload); err != nil && !errors.Is(err, ErrClosed) {
+				return 0, nil, c.drop(err)
+			}
+			continue
+		case opPong:
+			if c.pongHandler != nil {
+				c.pongHandler(string(f.payload))
+			}
+			continue
+		case opClose:
+			return 0, nil, c.handleClose(f.payload)
+		case opContinuation:
+			if !started {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			if started {
+				return 0, nil, c.fail(CloseProtocolError, "new message before the previous one ended")
+			}
+			started, messageOp, compressed = true, f.op, f.rsv1
+		}
+
+		if limit := c.rawLimit(compressed); limit > 0 && int64(len(payload))+int64(len(f.payload)) > limit {
+			return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+		}
+		payload = append(payload, f.payload...)
+		if !f.fin {
+			continue
+		}
+
+		if compressed {
+			var dict []byte
+			if !c.deflate.clientNoContextTakeover {
+				dict = c.readDict
+			}
+			inflated, err := decompress(payload, dict, c.cfg.readLimit)
+			if errors.Is(err, errTooBig) {
+				return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+			}
+			if err != nil {
+				return 0, nil, c.fail(CloseInvalidPayload, "invalid compressed data")
+			}
+			if !c.deflate.clientNoContextTakeover {
+				c.readDict = appendWindow(c.readDict, inflated)
+			}
+			payload = inflated
+		}
+		if messageOp == opText && !utf8.Valid(payload) {
+			return 0, nil, c.fail(CloseInvalidPayload, "invalid UTF-8 in text message")
+		}
+		if payload == nil {
+			payload = []byte{}
+		}
+		return MessageType(messageOp), payload, nil
+	}
+}
+
+// rawLimit is the largest payload to accumulate for a message. Compressed
+// data may be slightly larger than its content when it does not compress.
+func (c *Conn) rawLimit(compressed bool) int64 {
+	limit := c.cfg.readLimit
+	if compressed && limit > 0 {
+		limit += limit/1024 + 64
+	}
+	return limit
+}
+
+type frame struct {
+	fin     bool
+	rsv1    bool
+	op      byte
+	payload []byte
+}
+
+func (c *Conn) readFrame() (frame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return frame{}, c.drop(err)
+	}
+	f := frame{fin: head[0]&0x80 != 0, rsv1: head[0]&0x40 != 0, op: head[0] & 0x0f}
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7f)
+
+	switch {
+	case head[0]&0x30 != 0:
+		return f, c.fail(CloseProtocolError, "reserved bits set")
+	case f.rsv1 && (c.deflate == nil || f.op == opContinuation || f.op >= opClose):
+		return f, c.fail(CloseProtocolError, "unexpected compressed frame")
+	case f.op > opBinary && f.op < opClose || f.op > opPong:
+		return f, c.fail(CloseProtocolError, "unknown opcode "+strconv.Itoa(int(f.op)))
+	case masked != c.server:
+		return f, c.fail(CloseProtocolError, "frame masking is wrong for this side")
+	case f.op >= opClose && (!f.fin || length > maxControlPayload):
+		return f, c.fail(CloseProtocolError, "invalid control frame")
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return f, c.drop(err)
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return f, c.drop(err)
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+		if length>>63 != 0 {
+			return f, c.fail(CloseProtocolError, "invalid frame length")
+		}
+	}
+	if limit := c.rawLimit(f.rsv1); limit > 0 && f.op < opClose && length > uint64(limit) {
+		return f, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, key[:]); err != nil {
+			return f, c.drop(err)
+		}
+	}
+	f.payload = make([]byte, length)
+	if _, err := io.ReadFull(c.br, f.payload); err != nil {
+		return f, c.drop(err)
+	}
+	if masked {
+		maskBytes(key, f.payload)
+	}
+	return f, nil
+}
+
+// handleClose answers a close frame from the peer and closes the connection.
+func (c *Conn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatus}
+	switch {
+	case len(payload) == 1:
+		return c.fail(CloseProtocolError, "invalid close frame")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validCloseCode(closeErr.Code) {
+			return c.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.ValidString(closeErr.Text) {
+			return c.fail(CloseInvalidPayload, "invalid UTF-8 in close reason")
+		}
+	}
+
+	var reply []byte
+	if closeErr.Code != CloseNoStatus {
+		reply = closePayload(closeErr.Code, "")
+	}
+	c.writeControl(opClose, reply)
+	c.shutdown()
+	return closeErr
+}
+
+// fail closes the connection with code after the peer broke the protocol.
+func (c *Conn) fail(code int, reason string) error {
+	c.writeControl(opClose, closePayload(code, reason))
+	c.shutdown()
+	return &CloseError{Code: code, Text: reason}
+}
+
+// drop closes the connection after a read error. An unexpected end of the
+// stream is reported as CloseAbnormal, and any error after Close as
+// ErrClosed.
+func (c *Conn) drop(err error) error {
+	c.writeMu.Lock()
+	closing := c.closeSent
+	c.writeMu.Unlock()
+	c.shutdown()
+	if closing {
+		return ErrClosed
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &CloseError{Code: CloseAbnormal}
+	}
+	return err
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+func closePayload(code int, reason string) []byte {
+	if len(reason) > maxControlPayload-2 {
+		reason = reason[:maxControlPayload-2]
+		for !utf8.ValidString(reason) {
+			reason = reason[:len(reason)-1]
+		}
+	}
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	return append(payload, reason...)
+}
+
+// WriteMessage sends data as a single message, compressed when
+// permessage-deflate was negotiated and it makes the message smaller.
+func (c *Conn) WriteMessage(messageType MessageType, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("ws: invalid message type %d", messageType)
+	}
+	c.msgMu.Lock()
+	defer c.msgMu.Unlock()
+	if c.deflate != nil && len(data) >= compressThreshold {
+		if compressed := compress(data); len(compressed) < len(data) {
+			return c.writeFrame(byte(messageType), true, true, compressed)
+		}
+	}
+	return c.writeFrame(byte(messageType), true, false, data)
+}
+
+// NextWriter returns a writer for a message sent in fragments: each Write
+// sends one frame, and Close ends the message. Other messages wait until
+// then, but control frames may still be sent in between.
+func (c *Conn) NextWriter(messageType MessageType) (io.WriteCloser, error) {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return nil, fmt.Errorf("ws: invalid message type %d", messageType)
+	}
+	c.msgMu.Lock()
+	w := &messageWriter{c: c, op: byte(messageType)}
+	if c.deflate != nil {
+		w.fw = flateWriters.Get().(*flate.Writer)
+		w.fw.Reset(&w.buf)
+	}
+	return w, nil
+}
+
+type messageWriter struct {
+	c      *Conn
+	op     byte
+	fw     *flate.Writer
+	buf    bytes.Buffer
+	sent   bool
+	closed bool
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if w.fw == nil {
+		return len(p), w.frame(false, p)
+	}
+	w.fw.Write(p)
+	w.fw.Flush()
+	// Hold back the sync flush marker; it is only stripped at the end.
+	out := w.buf.Bytes()[:w.buf.Len()-4]
+	if err := w.frame(false, out); err != nil {
+		return 0, err
+	}
+	w.buf.Next(len(out))
+	return len(p), nil
+}
+
+func (w *messageWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.c.msgMu.Unlock()
+	if w.fw == nil {
+		return w.frame(true, nil)
+	}
+	w.fw.Flush()
+	flateWriters.Put(w.fw)
+	return w.frame(true, bytes.TrimSuffix(w.buf.Bytes(), deflateTail[:4]))
+}
+
+func (w *messageWriter) frame(fin bool, payload []byte) error {
+	op := byte(opContinuation)
+	if !w.sent {
+		op = w.op
+	}
+	err := w.c.writeFrame(op, fin, !w.sent && w.fw != nil, payload)
+	w.sent = true
+	return err
+}
+
+// Ping sends a ping with an optional payload of up to 125 bytes.
+func (c *Conn) Ping(data []byte) error {
+	if len(data) > maxControlPayload {
+		return errors.New("ws: ping payload too long")
+	}
+	return c.writeControl(opPing, data)
+}
+
+func (c *Conn) writeControl(op byte, payload []byte) error {
+	return c.writeFrame(op, true, false, payload)
+}
+
+func (c *Conn) writeFrame(op byte, fin, rsv1 bool, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return ErrClosed
+	}
+	if c.writeErr != nil {
+		return c.writeErr
+	}
+
+	buf := make([]byte, 0, 14+len(payload))
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	if rsv1 {
+		b0 |= 0x40
+	}
+	buf = append(buf, b0)
+
+	var maskBit byte
+	if !c.server {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xffff:
+		buf = binary.BigEndian.AppendUint16(append(buf, maskBit|126), uint16(n))
+	default:
+		buf = binary.BigEndian.AppendUint64(append(buf, maskBit|127), uint64(n))
+	}
+
+	if c.server {
+		buf = append(buf, payload...)
+	} else {
+		var key [4]byte
+		rand.Read(key[:])
+		buf = append(buf, key[:]...)
+		start := len(buf)
+		buf = append(buf, payload...)
+		maskBytes(key, buf[start:])
+	}
+
+	if op == opClose {
+		c.closeSent = true
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		c.writeErr = err
+		return err
+	}
+	return nil
+}
+
+// Close performs the closing handshake: it sends a close frame with code
+// and reason, waits up to the close timeout for the peer's answer and closes
+// the connection. Closing a closed connection does nothing.
+func (c *Conn) Close(code int, reason string) error {
+	select {
+	case <-c.closed:
+		return nil
+	default:
+	}
+
+	err := c.writeControl(opClose, closePayload(code, reason))
+	if errors.Is(err, ErrClosed) {
+		err = nil
+	}
+
+	if c.readMu.TryLock() {
+		// Nobody is reading, so wait for the answer here.
+		c.conn.SetReadDeadline(time.Now().Add(c.cfg.closeTimeout))
+		for c.readErr == nil {
+			if f, err := c.readFrame(); err != nil || f.op == opClose {
+				break
+			}
+		}
+		if c.readErr == nil {
+			c.readErr = ErrClosed
+		}
+		c.readMu.Unlock()
+	} else {
+		// ReadMessage will see the answer and close the connection.
+		select {
+		case <-c.closed:
+		case <-time.After(c.cfg.closeTimeout):
+		}
+	}
+	c.shutdown()
+	return err
+}
+
+func (c *Conn) shutdown() {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.conn.Close()
+	})
+}
+
+func maskBytes(key [4]byte, data []byte) {
+	for i := range data {
+		data[i] ^= key[i&3]
+	}
+}
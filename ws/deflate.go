@@ -0,0 +1,143 @@
+package ws
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// deflateParams are the negotiated permessage-deflate parameters. The server
+// always resets its compressor between messages.
+type deflateParams struct {
+	clientNoContextTakeover bool
+}
+
+func (p *deflateParams) String() string {
+	s := "permessage-deflate; server_no_context_takeover"
+	if p.clientNoContextTakeover {
+		s += "; client_no_context_takeover"
+	}
+	return s
+}
+
+// negotiateDeflate returns the parameters for the first permessage-deflate
+// offer the server can accept, or nil.
+func negotiateDeflate(h http.Header) *deflateParams {
+	for _, offer := range headerTokens(h, "Sec-WebSocket-Extensions") {
+		if params, ok := parseDeflateOffer(offer); ok {
+			return params
+		}
+	}
+	return nil
+}
+
+func parseDeflateOffer(offer string) (*deflateParams, bool) {
+	parts := strings.Split(offer, ";")
+	if strings.TrimSpace(parts[0]) != "permessage-deflate" {
+		return nil, false
+	}
+	params := &deflateParams{}
+	seen := map[string]bool{}
+	for _, part := range parts[1:] {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(part), "=")
+		name = strings.TrimSpace(name)
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if seen[name] {
+			return nil, false
+		}
+		seen[name] = true
+		switch name {
+		case "server_no_context_takeover", "client_no_context_takeover":
+			if hasValue {
+				return nil, false
+			}
+			if name == "client_no_context_takeover" {
+				params.clientNoContextTakeover = true
+			}
+		case "server_max_window_bits":
+			// compress/flate always uses a 32 KiB window.
+			if value != "15" {
+				return nil, false
+			}
+		case "client_max_window_bits":
+			// Any window up to 32 KiB can be decompressed.
+			if hasValue && !validWindowBits(value) {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func validWindowBits(value string) bool {
+	switch value {
+	case "8", "9", "10", "11", "12", "13", "14", "15":
+		return true
+	}
+	return false
+}
+
+// deflateTail is the end of a sync flush, which senders strip from each
+// message, followed by an empty final block so the reader sees io.EOF.
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+// maxWindow is the size of the deflate history window.
+const maxWindow = 32 << 10
+
+var flateWriters = sync.Pool{
+	New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+// compress returns data compressed as a permessage-deflate message.
+func compress(data []byte) []byte {
+	var buf bytes.Buffer
+	w := flateWriters.Get().(*flate.Writer)
+	w.Reset(&buf)
+	w.Write(data)
+	w.Flush()
+	flateWriters.Put(w)
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail[:4])
+}
+
+var errTooBig = errors.New("ws: message exceeds read limit")
+
+// decompress inflates a permessage-deflate message. dict is the history
+// kept when the client uses context takeover. limit bounds the inflated
+// size; zero or less means no limit.
+func decompress(data, dict []byte, limit int64) ([]byte, error) {
+	r := flate.NewReaderDict(io.MultiReader(bytes.NewReader(data), bytes.NewReader(deflateTail)), dict)
+	defer r.Close()
+	var src io.Reader = r
+	if limit > 0 {
+		src = io.LimitReader(r, limit+1)
+	}
+	out, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(len(out)) > limit {
+		return nil, errTooBig
+	}
+	return out, nil
+}
+
+// appendWindow returns the last 32 KiB of dict followed by data.
+func appendWindow(dict, data []byte) []byte {
+	if len(data) >= maxWindow {
+		return append([]byte(nil), data[len(data)-maxWindow:]...)
+	}
+	dict = append(dict, data...)
+	if len(dict) > maxWindow {
+		dict = append([]byte(nil), dict[len(dict)-maxWindow:]...)
+	}
+	return dict
+}
@@ -0,0 +1,244 @@
+// Package ws implements the server side of the WebSocket protocol (RFC 6455),
+// including fragmented messages, ping/pong, the close handshake, read limits
+// and the permessage-deflate extension (RFC 7692).
+package ws
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/BrunoCiccarino/GopherLight/req"
+)
+
+// DefaultReadLimit is the largest message, in bytes after decompression, a
+// connection accepts unless WithReadLimit changes it.
+const DefaultReadLimit int64 = 1 << 20
+
+// DefaultCloseTimeout is how long Close waits for the peer to answer a close
+// frame before dropping the connection.
+const DefaultCloseTimeout = 5 * time.Second
+
+// acceptGUID is appended to the client's key to compute Sec-WebSocket-Accept.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ErrBadHandshake is returned by Upgrade for requests that are not valid
+// WebSocket opening handshakes.
+var ErrBadHandshake = errors.New("ws: bad handshake")
+
+// ErrOriginNotAllowed is returned by Upgrade when the Origin check fails.
+var ErrOriginNotAllowed = errors.New("ws: origin not allowed")
+
+// Option configures Upgrade.
+type Option func(*config)
+
+type config struct {
+	checkOrigin  func(origin string) bool
+	cors         OriginPolicy
+	subprotocols []string
+	readLimit    int64
+	compression  bool
+	closeTimeout time.Duration
+}
+
+// OriginPolicy decides which cross-origin pages may connect.
+// middleware.CORSOptions implements it.
+type OriginPolicy interface {
+	AllowsOrigin(origin string) bool
+}
+
+// WithOriginCheck sets the function that decides whether a browser page
+// from origin may connect. By default only same-origin pages and clients
+// that send no Origin header, which are not browsers, are allowed. The
+// function replaces that check, so it must accept same-origin pages itself;
+// use WithCORS to add origins on top of it instead.
+func WithOriginCheck(allow func(origin string) bool) Option {
+	return func(c *config) {
+		c.checkOrigin = allow
+	}
+}
+
+// WithCORS lets pages from origins that policy allows connect, in addition
+// to same-origin pages. Pass the app's middleware.CORSOptions to reuse its
+// settings; with AllowOrigin "*" only same-origin pages are allowed, since
+// browsers send cookies with the handshake. WithOriginCheck takes precedence.
+func WithCORS(policy OriginPolicy) Option {
+	return func(c *config) {
+		c.cors = policy
+	}
+}
+
+// WithSubprotocols lists the subprotocols the server speaks, in order of
+// preference. The first one the client also offers is selected.
+func WithSubprotocols(protocols ...string) Option {
+	return func(c *config) {
+		c.subprotocols = protocols
+	}
+}
+
+// WithReadLimit sets the largest message, in bytes after decompression, the
+// connection accepts. Larger messages close the connection with
+// CloseMessageTooBig. Zero or less removes the limit.
+func WithReadLimit(limit int64) Option {
+	return func(c *config) {
+		c.readLimit = limit
+	}
+}
+
+// WithCompression accepts the permessage-deflate extension when the client
+// offers it. Messages are compressed when that makes them smaller.
+func WithCompression() Option {
+	return func(c *config) {
+		c.compression = true
+	}
+}
+
+// WithCloseTimeout sets how long Close waits for the peer's close frame.
+func WithCloseTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.closeTimeout = timeout
+	}
+}
+
+// reject answers a handshake that cannot be completed with RFC 7807 problem
+// details.
+func reject(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	_, res := req.New(w, r)
+	res.Problem(req.NewProblem(status, detail))
+}
+
+// IsUpgrade reports whether r asks to switch to the WebSocket protocol.
+func IsUpgrade(r *http.Request) bool {
+	return headerHasToken(r.Header, "Connection", "upgrade") &&
+		headerHasToken(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade completes the opening handshake and takes over the connection. On
+// failure it answers the request with problem details and returns the reason:
+// 400 for malformed handshakes, 403 for disallowed origins and 426 for
+// unsupported versions.
+func Upgrade(w http.ResponseWriter, r *http.Request, opts ...Option) (*Conn, error) {
+	cfg := config{readLimit: DefaultReadLimit, closeTimeout: DefaultCloseTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if r.Method != http.MethodGet || !IsUpgrade(r) {
+		reject(w, r, http.StatusBadRequest, "Not a WebSocket handshake")
+		return nil, ErrBadHandshake
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		reject(w, r, http.StatusUpgradeRequired, "Unsupported WebSocket version")
+		return nil, ErrBadHandshake
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		reject(w, r, http.StatusBadRequest, "Invalid Sec-WebSocket-Key")
+		return nil, ErrBadHandshake
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		allowed := cfg.checkOrigin
+		if allowed == nil {
+			allowed = func(origin string) bool {
+				return sameOrigin(origin, r.Host) || (cfg.cors != nil && cfg.cors.AllowsOrigin(origin))
+			}
+		}
+		if !allowed(origin) {
+			reject(w, r, http.StatusForbidden, "Origin not allowed")
+			return nil, ErrOriginNotAllowed
+		}
+	}
+
+	protocol := selectSubprotocol(r.Header, cfg.subprotocols)
+	var deflate *deflateParams
+	if cfg.compression {
+		deflate = negotiateDeflate(r.Header)
+	}
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		reject(w, r, http.StatusInternalServerError, "WebSocket upgrade not supported")
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n")
+	if protocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + protocol + "\r\n")
+	}
+	if deflate != nil {
+		b.WriteString("Sec-WebSocket-Extensions: " + deflate.String() + "\r\n")
+	}
+	b.WriteString("\r\n")
+	// Clear any deadlines set by the server, such as its write timeout.
+	netConn.SetDeadline(time.Time{})
+	if _, err := brw.WriteString(b.String()); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+	if err := brw.Flush(); err != nil {
+		netConn.Close()
+		return nil, err
+	}
+
+	c := newConn(netConn, brw.Reader, true, cfg)
+	c.request = r
+	c.subprotocol = protocol
+	c.deflate = deflate
+	return c, nil
+}
+
+// AcceptKey returns the Sec-WebSocket-Accept value for a Sec-WebSocket-Key.
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// sameOrigin reports whether origin names the host the request was sent to.
+func sameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, host)
+}
+
+func selectSubprotocol(h http.Header, supported []string) string {
+	offered := headerTokens(h, "Sec-WebSocket-Protocol")
+	for _, protocol := range supported {
+		for _, offer := range offered {
+			if offer == protocol {
+				return protocol
+			}
+		}
+	}
+	return ""
+}
+
+// headerTokens returns the comma-separated tokens of every value of key.
+func headerTokens(h http.Header, key string) []string {
+	var tokens []string
+	for _, value := range h.Values(key) {
+		for _, token := range strings.Split(value, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+func headerHasToken(h http.Header, key, token string) bool {
+	for _, t := range headerTokens(h, key) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,378 @@
+package ws
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BrunoCiccarino/GopherLight/req"
+)
+
+// dial performs the opening handshake against srv and returns a client Conn
+// and the handshake response. extra headers are added to the request.
+func dial(t *testing.T, srv *httptest.Server, extra http.Header) (*Conn, *http.Response) {
+	t.Helper()
+	netConn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	t.Cleanup(func() { netConn.Close() })
+
+	keyBytes := make([]byte, 16)
+	rand.Read(keyBytes)
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	r, _ := http.NewRequest("GET", srv.URL+"/", nil)
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Sec-WebSocket-Version", "13")
+	r.Header.Set("Sec-WebSocket-Key", key)
+	for name, values := range extra {
+		r.Header[name] = values
+	}
+	if err := r.Write(netConn); err != nil {
+		t.Fatalf("Failed to send handshake: %v", err)
+	}
+
+	br := bufio.NewReader(netConn)
+	resp, err := http.ReadResponse(br, r)
+	if err != nil {
+		t.Fatalf("Failed to read handshake response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, resp
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != AcceptKey(key) {
+		t.Fatalf("Expected Sec-WebSocket-Accept %s, got %s", AcceptKey(key), got)
+	}
+
+	c := newConn(netConn, br, false, config{closeTimeout: time.Second})
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); strings.HasPrefix(ext, "permessage-deflate") {
+		c.deflate = &deflateParams{}
+	}
+	return c, resp
+}
+
+// serve starts a server upgrading every request with opts and passing the
+// connection to handler.
+func serve(t *testing.T, handler func(*Conn), opts ...Option) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, opts...)
+		if err != nil {
+			return
+		}
+		defer conn.Close(CloseNormal, "")
+		handler(conn)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// echo sends every message back until the connection closes.
+func echo(conn *Conn) {
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if err := conn.WriteMessage(messageType, data); err != nil {
+			return
+		}
+	}
+}
+
+// writeRaw sends a masked client frame with the given first byte.
+func writeRaw(t *testing.T, c *Conn, b0 byte, payload []byte) {
+	t.Helper()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	key := [4]byte{1, 2, 3, 4}
+	buf := []byte{b0}
+	switch n := len(payload); {
+	case n < 126:
+		buf = append(buf, 0x80|byte(n))
+	case n <= 0xffff:
+		buf = binary.BigEndian.AppendUint16(append(buf, 0x80|126), uint16(n))
+	default:
+		buf = binary.BigEndian.AppendUint64(append(buf, 0x80|127), uint64(n))
+	}
+	buf = append(buf, key[:]...)
+	masked := append([]byte(nil), payload...)
+	maskBytes(key, masked)
+	if _, err := c.conn.Write(append(buf, masked...)); err != nil {
+		t.Fatalf("Failed to write raw frame: %v", err)
+	}
+}
+
+// expectClose reads until the server closes the connection and checks the
+// close code it sent.
+func expectClose(t *testing.T, c *Conn, code int) {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := c.ReadMessage()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != code {
+		t.Fatalf("Expected close code %d, got %v", code, err)
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	// The example from RFC 6455, section 1.3.
+	if got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected accept key %s", got)
+	}
+}
+
+func TestEcho(t *testing.T) {
+	c, _ := dial(t, serve(t, echo), nil)
+
+	for _, msg := range []struct {
+		messageType MessageType
+		data        []byte
+	}{
+		{TextMessage, []byte("hello")},
+		{BinaryMessage, []byte{0, 1, 2, 255}},
+		{TextMessage, bytes.Repeat([]byte("x"), 300)},
+		{BinaryMessage, bytes.Repeat([]byte{7}, 70000)},
+		{TextMessage, []byte{}},
+	} {
+		if err := c.WriteMessage(msg.messageType, msg.data); err != nil {
+			t.Fatalf("WriteMessage returned error: %v", err)
+		}
+		messageType, data, err := c.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage returned error: %v", err)
+		}
+		if messageType != msg.messageType || !bytes.Equal(data, msg.data) {
+			t.Fatalf("Expected echo of %d bytes, got type %d with %d bytes", len(msg.data), messageType, len(data))
+		}
+	}
+}
+
+func TestFragmentedMessageWithInterleavedPing(t *testing.T) {
+	c, _ := dial(t, serve(t, echo), nil)
+
+	writeRaw(t, c, opText, []byte("Hel"))
+	writeRaw(t, c, 0x80|opPing, []byte("are you there"))
+	writeRaw(t, c, opContinuation, []byte("lo, "))
+	writeRaw(t, c, 0x80|opContinuation, []byte("world"))
+
+	var pong string
+	c.SetPongHandler(func(data string) { pong = data })
+	_, data, err := c.ReadMessage()
+	if err != nil {
+		t.Fatalf("ReadMessage returned error: %v", err)
+	}
+	if string(data) != "Hello, world" {
+		t.Fatalf("Expected reassembled message, got '%s'", data)
+	}
+	if pong != "are you there" {
+		t.Fatalf("Expected pong with the ping payload, got '%s'", pong)
+	}
+}
+
+func TestNextWriterFragments(t *testing.T) {
+	received := make(chan []byte, 1)
+	srv := serve(t, func(conn *Conn) {
+		_, data, _ := conn.ReadMessage()
+		received <- data
+	})
+	c, _ := dial(t, srv, nil)
+
+	w, _ := c.NextWriter(TextMessage)
+	io.WriteString(w, "part one, ")
+	c.Ping([]byte("between fragments"))
+	io.WriteString(w, "part two")
+	w.Close()
+
+	select {
+	case data := <-received:
+		if string(data) != "part one, part two" {
+			t.Fatalf("Expected the fragments to be joined, got '%s'", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the fragmented message")
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	srv := serve(t, func(conn *Conn) {
+		conn.Close(CloseGoingAway, "server restarting")
+	})
+	c, _ := dial(t, srv, nil)
+
+	_, _, err := c.ReadMessage()
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != CloseGoingAway || closeErr.Text != "server restarting" {
+		t.Fatalf("Expected close 1001 with reason, got %v", err)
+	}
+	if _, _, err := c.ReadMessage(); err != closeErr {
+		t.Fatalf("Expected the same error on later reads, got %v", err)
+	}
+}
+
+func TestClientInitiatedClose(t *testing.T) {
+	result := make(chan error, 1)
+	srv := serve(t, func(conn *Conn) {
+		_, _, err := conn.ReadMessage()
+		result <- err
+	})
+	c, _ := dial(t, srv, nil)
+
+	if err := c.Close(4000, "bye"); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	err := <-result
+	var closeErr *CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != 4000 || closeErr.Text != "bye" {
+		t.Fatalf("Expected the server to see close 4000, got %v", err)
+	}
+}
+
+func TestProtocolViolations(t *testing.T) {
+	tests := []struct {
+		name    string
+		b0      byte
+		payload []byte
+		code    int
+	}{
+		{"reserved bits", 0x80 | 0x20 | opText, []byte("x"), CloseProtocolError},
+		{"compressed without extension", 0x80 | 0x40 | opText, []byte("x"), CloseProtocolError},
+		{"unknown opcode", 0x80 | 0x3, nil, CloseProtocolError},
+		{"fragmented control frame", opPing, nil, CloseProtocolError},
+		{"long control frame", 0x80 | opPing, make([]byte, 126), CloseProtocolError},
+		{"orphan continuation", 0x80 | opContinuation, []byte("x"), CloseProtocolError},
+		{"invalid UTF-8", 0x80 | opText, []byte{0xff, 0xfe}, CloseInvalidPayload},
+		{"one-byte close", 0x80 | opClose, []byte{3}, CloseProtocolError},
+		{"reserved close code", 0x80 | opClose, []byte{0x03, 0xed}, CloseProtocolError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := dial(t, serve(t, echo), nil)
+			writeRaw(t, c, tt.b0, tt.payload)
+			expectClose(t, c, tt.code)
+		})
+	}
+}
+
+func TestUnmaskedClientFrame(t *testing.T) {
+	c, _ := dial(t, serve(t, echo), nil)
+	c.conn.Write([]byte{0x80 | opText, 2, 'h', 'i'})
+	expectClose(t, c, CloseProtocolError)
+}
+
+func TestReadLimit(t *testing.T) {
+	c, _ := dial(t, serve(t, echo, WithReadLimit(1024)), nil)
+
+	c.WriteMessage(BinaryMessage, make([]byte, 1024))
+	if _, data, err := c.ReadMessage(); err != nil || len(data) != 1024 {
+		t.Fatalf("Expected a message at the limit to pass, got %d bytes and %v", len(data), err)
+	}
+	c.WriteMessage(BinaryMessage, make([]byte, 1025))
+	expectClose(t, c, CloseMessageTooBig)
+}
+
+func TestCompression(t *testing.T) {
+	header := http.Header{"Sec-Websocket-Extensions": {"permessage-deflate; client_max_window_bits"}}
+	c, resp := dial(t, serve(t, echo, WithCompression()), header)
+
+	if ext := resp.Header.Get("Sec-WebSocket-Extensions"); ext != "permessage-deflate; server_no_context_takeover" {
+		t.Fatalf("Unexpected extension response: %s", ext)
+	}
+
+	message := bytes.Repeat([]byte("compress me please "), 200)
+	for i := 0; i < 3; i++ {
+		if err := c.WriteMessage(TextMessage, message); err != nil {
+			t.Fatalf("WriteMessage returned error: %v", err)
+		}
+		_, data, err := c.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage returned error: %v", err)
+		}
+		if !bytes.Equal(data, message) {
+			t.Fatalf("Compressed echo mismatch: got %d bytes", len(data))
+		}
+	}
+
+	w, _ := c.NextWriter(TextMessage)
+	w.Write(message[:1000])
+	w.Write(message[1000:])
+	w.Close()
+	if _, data, err := c.ReadMessage(); err != nil || !bytes.Equal(data, message) {
+		t.Fatalf("Expected fragmented compressed echo, got %d bytes and %v", len(data), err)
+	}
+}
+
+func TestCompressionBombIsRejected(t *testing.T) {
+	header := http.Header{"Sec-Websocket-Extensions": {"permessage-deflate"}}
+	c, _ := dial(t, serve(t, echo, WithCompression(), WithReadLimit(4096)), header)
+
+	bomb := compress(make([]byte, 1<<20))
+	writeRaw(t, c, 0x80|0x40|opBinary, bomb)
+	expectClose(t, c, CloseMessageTooBig)
+}
+
+func TestDeflateNegotiation(t *testing.T) {
+	tests := []struct {
+		offer string
+		want  string
+	}{
+		{"permessage-deflate", "permessage-deflate; server_no_context_takeover"},
+		{"permessage-deflate; client_no_context_takeover", "permessage-deflate; server_no_context_takeover; client_no_context_takeover"},
+		{"permessage-deflate; server_max_window_bits=10, permessage-deflate", "permessage-deflate; server_no_context_takeover"},
+		{"permessage-deflate; server_max_window_bits=10", ""},
+		{"permessage-deflate; unknown", ""},
+		{"x-webkit-deflate-frame", ""},
+	}
+
+	for _, tt := range tests {
+		params := negotiateDeflate(http.Header{"Sec-Websocket-Extensions": {tt.offer}})
+		got := ""
+		if params != nil {
+			got = params.String()
+		}
+		if got != tt.want {
+			t.Errorf("negotiateDeflate(%q) = %q, want %q", tt.offer, got, tt.want)
+		}
+	}
+}
+
+func TestHandshakeChecks(t *testing.T) {
+	srv := serve(t, echo, WithSubprotocols("v2.chat", "chat"))
+
+	_, resp := dial(t, srv, http.Header{"Origin": {"https://evil.example"}})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected a cross-origin handshake to be refused, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != req.ProblemContentType {
+		t.Fatalf("Expected a problem response, got %s", ct)
+	}
+	if _, resp := dial(t, srv, http.Header{"Sec-Websocket-Version": {"8"}}); resp.StatusCode != http.StatusUpgradeRequired {
+		t.Fatalf("Expected 426 for an old version, got %d", resp.StatusCode)
+	}
+	if _, resp := dial(t, srv, http.Header{"Sec-Websocket-Key": {"short"}}); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for an invalid key, got %d", resp.StatusCode)
+	}
+
+	origin := http.Header{"Origin": {srv.URL}, "Sec-Websocket-Protocol": {"chat, v2.chat"}}
+	if _, resp := dial(t, srv, origin); resp.Header.Get("Sec-WebSocket-Protocol") != "v2.chat" {
+		t.Fatalf("Expected the server's preferred subprotocol, got %q", resp.Header.Get("Sec-WebSocket-Protocol"))
+	}
+
+	allowed := serve(t, echo, WithOriginCheck(func(origin string) bool { return origin == "https://app.example" }))
+	if c, resp := dial(t, allowed, http.Header{"Origin": {"https://app.example"}}); c == nil {
+		t.Fatalf("Expected the custom origin check to allow the handshake, got %d", resp.StatusCode)
+	}
+}